@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Dreamacro/clash/common/queue"
+	"github.com/Dreamacro/clash/component/dialer"
 	C "github.com/Dreamacro/clash/constant"
 )
 
@@ -18,18 +19,72 @@ var (
 
 type Base struct {
 	name string
+	addr string
 	tp   C.AdapterType
 	udp  bool
+
+	// dialer is the proxy the connections to the server go through, nil for a direct one
+	dialer C.Proxy
 }
 
 func (b *Base) Name() string {
 	return b.name
 }
 
+func (b *Base) Addr() string {
+	return b.addr
+}
+
 func (b *Base) Type() C.AdapterType {
 	return b.tp
 }
 
+// SetDialer sets the proxy the connections to the server go through
+func (b *Base) SetDialer(dialer C.Proxy) {
+	b.dialer = dialer
+}
+
+// dialContext dials the proxy server at address
+func (b *Base) dialContext(ctx context.Context, address string) (net.Conn, error) {
+	if b.dialer == nil {
+		c, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		tcpKeepAlive(c)
+		return c, nil
+	}
+
+	metadata, err := AddrToMetadata(address, C.TCP)
+	if err != nil {
+		return nil, err
+	}
+	return b.dialer.DialContext(ctx, metadata)
+}
+
+// DialServer dials the proxy server like the connections of the adapter do,
+// through its dialer if one is set
+func (b *Base) DialServer(ctx context.Context) (net.Conn, error) {
+	return b.dialContext(ctx, b.addr)
+}
+
+// listenPacket returns a packet conn to send packets to the proxy server at address
+func (b *Base) listenPacket(address string) (net.PacketConn, error) {
+	if b.dialer == nil {
+		return dialer.ListenPacket("udp", "")
+	}
+
+	metadata, err := AddrToMetadata(address, C.UDP)
+	if err != nil {
+		return nil, err
+	}
+	return b.dialer.DialUDP(metadata)
+}
+
+func (b *Base) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
+	return nil, errors.New("no support")
+}
+
 func (b *Base) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
 	return nil, errors.New("no support")
 }
@@ -44,8 +99,12 @@ func (b *Base) MarshalJSON() ([]byte, error) {
 	})
 }
 
-func NewBase(name string, tp C.AdapterType, udp bool) *Base {
-	return &Base{name, tp, udp}
+func (b *Base) Unwrap(metadata *C.Metadata) C.Proxy {
+	return nil
+}
+
+func NewBase(name string, addr string, tp C.AdapterType, udp bool) *Base {
+	return &Base{name: name, addr: addr, tp: tp, udp: udp}
 }
 
 type conn struct {
@@ -61,6 +120,11 @@ func (c *conn) AppendToChains(a C.ProxyAdapter) {
 	c.chain = append(c.chain, a.Name())
 }
 
+// NewConn wraps c as a connection of the adapter a
+func NewConn(c net.Conn, a C.ProxyAdapter) C.Conn {
+	return newConn(c, a)
+}
+
 func newConn(c net.Conn, a C.ProxyAdapter) C.Conn {
 	return &conn{c, []string{a.Name()}}
 }
@@ -93,6 +157,13 @@ type Proxy struct {
 	alive   bool
 }
 
+// SetDialer routes the connections to the proxy server through dialer
+func (p *Proxy) SetDialer(dialer C.Proxy) {
+	if adapter, ok := p.ProxyAdapter.(interface{ SetDialer(C.Proxy) }); ok {
+		adapter.SetDialer(dialer)
+	}
+}
+
 func (p *Proxy) Alive() bool {
 	return p.alive
 }
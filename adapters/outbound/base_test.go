@@ -0,0 +1,53 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordDialer dials directly and records the addresses it is asked for
+type recordDialer struct {
+	*Base
+	addrs chan string
+}
+
+func (d *recordDialer) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	d.addrs <- metadata.RemoteAddress()
+	c, err := net.Dial("tcp", metadata.RemoteAddress())
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c, d), nil
+}
+
+func TestBase_Dialer(t *testing.T) {
+	proxy, requests, closer := newTestTrojan(t, "password")
+	defer closer()
+
+	dialer := &recordDialer{Base: NewBase("dialer", "", C.Direct, false), addrs: make(chan string, 1)}
+	proxy.(*Proxy).SetDialer(NewProxy(dialer))
+
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "443"}
+	c, err := proxy.Dial(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+	assert.Equal(t, proxy.Addr(), <-dialer.addrs)
+
+	msg := []byte("hello dialer")
+	_, err = c.Write(msg)
+	assert.Nil(t, err)
+	assert.Equal(t, "example.org:443", (<-requests).addr)
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+}
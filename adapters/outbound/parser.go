@@ -52,6 +52,13 @@ func ParseProxy(mapping map[string]interface{}) (C.Proxy, error) {
 			break
 		}
 		proxy, err = NewVmess(*vmessOption)
+	case "vless":
+		vlessOption := &VlessOption{}
+		err = decoder.Decode(mapping, vlessOption)
+		if err != nil {
+			break
+		}
+		proxy, err = NewVless(*vlessOption)
 	case "snell":
 		snellOption := &SnellOption{}
 		err = decoder.Decode(mapping, snellOption)
@@ -59,6 +66,27 @@ func ParseProxy(mapping map[string]interface{}) (C.Proxy, error) {
 			break
 		}
 		proxy, err = NewSnell(*snellOption)
+	case "trojan":
+		trojanOption := &TrojanOption{}
+		err = decoder.Decode(mapping, trojanOption)
+		if err != nil {
+			break
+		}
+		proxy = NewTrojan(*trojanOption)
+	case "wireguard":
+		wgOption := &WireGuardOption{}
+		err = decoder.Decode(mapping, wgOption)
+		if err != nil {
+			break
+		}
+		proxy, err = NewWireGuard(*wgOption)
+	case "ssh":
+		sshOption := &SSHOption{}
+		err = decoder.Decode(mapping, sshOption)
+		if err != nil {
+			break
+		}
+		proxy, err = NewSSH(*sshOption)
 	default:
 		return nil, fmt.Errorf("Unsupport proxy type: %s", proxyType)
 	}
@@ -7,9 +7,10 @@ import (
 	"net"
 	"strconv"
 
-	"github.com/Dreamacro/clash/component/dialer"
+	SSRPacket "github.com/Dreamacro/clash/component/ssr"
 	C "github.com/Dreamacro/clash/constant"
 
+	"github.com/Dreamacro/go-shadowsocks2/core"
 	SSRUtils "github.com/sh4d0wfiend/go-shadowsocksr"
 	SSRObfs "github.com/sh4d0wfiend/go-shadowsocksr/obfs"
 	SSRProtocol "github.com/sh4d0wfiend/go-shadowsocksr/protocol"
@@ -18,7 +19,6 @@ import (
 
 type ShadowSocksR struct {
 	*Base
-	server string
 
 	cipher   string
 	password string
@@ -32,6 +32,10 @@ type ShadowSocksR struct {
 	obfs          string
 	obfsParam     string
 	obfsData      interface{}
+
+	// udp
+	udpCipher   core.Cipher
+	udpProtocol *SSRPacket.Protocol
 }
 
 type ShadowSocksROption struct {
@@ -44,26 +48,18 @@ type ShadowSocksROption struct {
 	ProtocolParam string `proxy:"protocol-param"`
 	Obfs          string `proxy:"obfs"`
 	ObfsParam     string `proxy:"obfs-param"`
-
-	// TODO: Add UDP support
-	// UDP bool `proxy:"udp,omitempty"`
+	UDP           bool   `proxy:"udp,omitempty"`
 }
 
-func (ssr *ShadowSocksR) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
-	c, err := dialer.DialContext(ctx, "tcp", ssr.server)
-	if err != nil {
-		return nil, fmt.Errorf("%s connect error: %w", ssr.server, err)
-	}
-	tcpKeepAlive(c)
-
+func (ssr *ShadowSocksR) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
 	cipher, err := SSRUtils.NewStreamCipher(ssr.cipher, ssr.password)
 	if err != nil {
-		return nil, fmt.Errorf("ssr %s initialize error: %w", ssr.server, err)
+		return nil, fmt.Errorf("ssr %s initialize error: %w", ssr.addr, err)
 	}
 
 	ssconn := SSRUtils.NewSSTCPConn(c, cipher)
 	if ssconn.Conn == nil || ssconn.RemoteAddr() == nil {
-		return nil, fmt.Errorf("%s connect error: cannot establish connection", ssr.server)
+		return nil, fmt.Errorf("%s connect error: cannot establish connection", ssr.addr)
 	}
 
 	ssconn.IObfs = SSRObfs.NewObfs(ssr.obfs)
@@ -88,10 +84,24 @@ func (ssr *ShadowSocksR) DialContext(ctx context.Context, metadata *C.Metadata)
 
 	addr := serializesSocksAddr(metadata)
 	if _, err := ssconn.Write(addr); err != nil {
-		return nil, fmt.Errorf("%s connect error: %w", ssr.server, err)
+		return nil, fmt.Errorf("%s connect error: %w", ssr.addr, err)
+	}
+
+	return ssconn, nil
+}
+
+func (ssr *ShadowSocksR) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	c, err := ssr.dialContext(ctx, ssr.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", ssr.addr, err)
+	}
+
+	c, err = ssr.StreamConn(c, metadata)
+	if err != nil {
+		return nil, err
 	}
 
-	return newConn(ssconn, ssr), nil
+	return newConn(c, ssr), nil
 }
 
 func (ssr *ShadowSocksR) MarshalJSON() ([]byte, error) {
@@ -100,17 +110,54 @@ func (ssr *ShadowSocksR) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (ssr *ShadowSocksR) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	pc, err := ssr.listenPacket(ssr.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := resolveUDPAddr("udp", ssr.addr)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	pc = ssr.udpProtocol.PacketConn(ssr.udpCipher.PacketConn(pc))
+	return newPacketConn(&ssPacketConn{PacketConn: pc, rAddr: addr}, ssr), nil
+}
+
 func NewShadowSocksR(option ShadowSocksROption) (*ShadowSocksR, error) {
 	server := net.JoinHostPort(option.Server, strconv.Itoa(option.Port))
 
+	var udpCipher core.Cipher
+	var udpProtocol *SSRPacket.Protocol
+	if option.UDP {
+		cipher, err := SSRUtils.NewStreamCipher(option.Cipher, option.Password)
+		if err != nil {
+			return nil, fmt.Errorf("ssr %s initialize error: %w", server, err)
+		}
+		key, _ := cipher.Key()
+
+		// the packets are encrypted like shadowsocks stream ciphers
+		udpCipher, err = core.PickCipher(option.Cipher, key, "")
+		if err != nil {
+			return nil, fmt.Errorf("ssr %s udp is not supported with cipher %s: %w", server, option.Cipher, err)
+		}
+
+		udpProtocol, err = SSRPacket.NewProtocol(option.Protocol, option.ProtocolParam, key)
+		if err != nil {
+			return nil, fmt.Errorf("ssr %s initialize error: %w", server, err)
+		}
+	}
+
 	return &ShadowSocksR{
 		Base: &Base{
 			name: option.Name,
+			addr: server,
 			tp:   C.ShadowsocksR,
-			udp:  false,
+			udp:  option.UDP,
 		},
 
-		server:   server,
 		cipher:   option.Cipher,
 		password: option.Password,
 
@@ -121,5 +168,8 @@ func NewShadowSocksR(option ShadowSocksROption) (*ShadowSocksR, error) {
 		obfsParam:     option.ObfsParam,
 		protocol:      option.Protocol,
 		protocolParam: option.ProtocolParam,
+
+		udpCipher:   udpCipher,
+		udpProtocol: udpProtocol,
 	}, nil
 }
@@ -7,7 +7,6 @@ import (
 	"strconv"
 
 	"github.com/Dreamacro/clash/common/structure"
-	"github.com/Dreamacro/clash/component/dialer"
 	obfs "github.com/Dreamacro/clash/component/simple-obfs"
 	"github.com/Dreamacro/clash/component/snell"
 	C "github.com/Dreamacro/clash/constant"
@@ -15,8 +14,8 @@ import (
 
 type Snell struct {
 	*Base
-	server     string
 	psk        []byte
+	version    int
 	obfsOption *simpleObfsOption
 }
 
@@ -25,32 +24,70 @@ type SnellOption struct {
 	Server   string                 `proxy:"server"`
 	Port     int                    `proxy:"port"`
 	Psk      string                 `proxy:"psk"`
+	UDP      bool                   `proxy:"udp,omitempty"`
+	Version  int                    `proxy:"version,omitempty"`
 	ObfsOpts map[string]interface{} `proxy:"obfs-opts,omitempty"`
 }
 
-func (s *Snell) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
-	c, err := dialer.DialContext(ctx, "tcp", s.server)
-	if err != nil {
-		return nil, fmt.Errorf("%s connect error: %w", s.server, err)
-	}
-	tcpKeepAlive(c)
+func (s *Snell) streamConn(c net.Conn) net.Conn {
 	switch s.obfsOption.Mode {
 	case "tls":
 		c = obfs.NewTLSObfs(c, s.obfsOption.Host)
 	case "http":
-		_, port, _ := net.SplitHostPort(s.server)
+		_, port, _ := net.SplitHostPort(s.addr)
 		c = obfs.NewHTTPObfs(c, s.obfsOption.Host, port)
 	}
-	c = snell.StreamConn(c, s.psk)
+	return snell.StreamConn(c, s.psk)
+}
+
+func (s *Snell) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
+	c = s.streamConn(c)
 	port, _ := strconv.Atoi(metadata.DstPort)
-	err = snell.WriteHeader(c, metadata.String(), uint(port))
+	err := snell.WriteHeader(c, metadata.String(), uint(port))
+	return c, err
+}
+
+func (s *Snell) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	c, err := s.dialContext(ctx, s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", s.addr, err)
+	}
+
+	c, err = s.StreamConn(c, metadata)
 	return newConn(c, s), err
 }
 
+func (s *Snell) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tcpTimeout)
+	defer cancel()
+	c, err := s.dialContext(ctx, s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", s.addr, err)
+	}
+
+	c = s.streamConn(c)
+	if err := snell.WriteUDPHeader(c, s.version); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return newPacketConn(&snellPacketConn{Conn: c}, s), nil
+}
+
 func NewSnell(option SnellOption) (*Snell, error) {
 	server := net.JoinHostPort(option.Server, strconv.Itoa(option.Port))
 	psk := []byte(option.Psk)
 
+	version := option.Version
+	if version == 0 {
+		version = snell.Version1
+	}
+	if version < snell.Version1 || version > snell.Version3 {
+		return nil, fmt.Errorf("snell %s version error: %d", server, version)
+	}
+	if option.UDP && version < snell.Version3 {
+		return nil, fmt.Errorf("snell %s udp requires version 3", server)
+	}
+
 	decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
 	obfsOption := &simpleObfsOption{Host: "bing.com"}
 	if err := decoder.Decode(option.ObfsOpts, obfsOption); err != nil {
@@ -64,10 +101,34 @@ func NewSnell(option SnellOption) (*Snell, error) {
 	return &Snell{
 		Base: &Base{
 			name: option.Name,
+			addr: server,
 			tp:   C.Snell,
+			udp:  option.UDP,
 		},
-		server:     server,
 		psk:        psk,
+		version:    version,
 		obfsOption: obfsOption,
 	}, nil
 }
+
+type snellPacketConn struct {
+	net.Conn
+}
+
+func (spc *snellPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	uAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, fmt.Errorf("unsupported addr: %s", addr)
+	}
+	return snell.WritePacket(spc.Conn, uAddr.IP.String(), uint(uAddr.Port), b)
+}
+
+func (spc *snellPacketConn) WriteWithMetadata(p []byte, metadata *C.Metadata) (n int, err error) {
+	port, _ := strconv.Atoi(metadata.DstPort)
+	return snell.WritePacket(spc.Conn, metadata.String(), uint(port), p)
+}
+
+func (spc *snellPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	addr, n, err := snell.ReadPacket(spc.Conn, b)
+	return n, addr, err
+}
@@ -0,0 +1,27 @@
+package outbound
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnell_UDPVersion(t *testing.T) {
+	option := SnellOption{Name: "snell", Server: "127.0.0.1", Port: 443, Psk: "psk", UDP: true, ObfsOpts: map[string]interface{}{"mode": "tls"}}
+
+	// the older versions have no udp
+	_, err := NewSnell(option)
+	assert.NotNil(t, err)
+	option.Version = 2
+	_, err = NewSnell(option)
+	assert.NotNil(t, err)
+
+	option.Version = 3
+	s, err := NewSnell(option)
+	assert.Nil(t, err)
+	assert.True(t, s.SupportUDP())
+
+	option.Version = 4
+	_, err = NewSnell(option)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,265 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	C "github.com/Dreamacro/clash/constant"
+	"github.com/Dreamacro/clash/log"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type SSH struct {
+	*Base
+	config *ssh.ClientConfig
+
+	mux    sync.Mutex
+	client *ssh.Client
+}
+
+type SSHOption struct {
+	Name                 string   `proxy:"name"`
+	Server               string   `proxy:"server"`
+	Port                 int      `proxy:"port"`
+	UserName             string   `proxy:"username"`
+	Password             string   `proxy:"password,omitempty"`
+	PrivateKey           string   `proxy:"private-key,omitempty"`
+	PrivateKeyPassphrase string   `proxy:"private-key-passphrase,omitempty"`
+	HostKey              []string `proxy:"host-key,omitempty"`
+	SkipHostKeyVerify    bool     `proxy:"skip-host-key-verify,omitempty"`
+}
+
+// getClient returns the pooled ssh connection, a new one is established
+// when there is none or the previous one dropped
+func (s *SSH) getClient(ctx context.Context) (*ssh.Client, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	c, err := s.dialContext(ctx, s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", s.addr, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(c, s.addr, s.config)
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s ssh handshake error: %w", s.addr, err)
+	}
+	c.SetDeadline(time.Time{})
+
+	client := ssh.NewClient(conn, chans, reqs)
+	s.client = client
+	go func() {
+		client.Wait()
+		s.dropClient(client)
+	}()
+	return client, nil
+}
+
+func (s *SSH) dropClient(client *ssh.Client) {
+	s.mux.Lock()
+	if s.client == client {
+		s.client = nil
+	}
+	s.mux.Unlock()
+	client.Close()
+}
+
+// StreamConn runs a dedicated ssh session over c, which is closed along with
+// the returned conn
+func (s *SSH) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
+	conn, chans, reqs, err := ssh.NewClientConn(c, s.addr, s.config)
+	if err != nil {
+		return nil, fmt.Errorf("%s ssh handshake error: %w", s.addr, err)
+	}
+
+	client := ssh.NewClient(conn, chans, reqs)
+	ch, err := client.Dial("tcp", metadata.RemoteAddress())
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s connect error: %w", s.addr, err)
+	}
+	return &sshConn{Conn: ch, client: client}, nil
+}
+
+func (s *SSH) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	var err error
+	// retry once on a fresh connection when the pooled one is broken
+	for i := 0; i < 2; i++ {
+		var client *ssh.Client
+		client, err = s.getClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var c net.Conn
+		c, err = client.Dial("tcp", metadata.RemoteAddress())
+		if err == nil {
+			return newConn(&sshConn{Conn: c}, s), nil
+		}
+
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			break
+		}
+		s.dropClient(client)
+	}
+	return nil, fmt.Errorf("%s connect error: %w", s.addr, err)
+}
+
+// Close closes the pooled ssh connection, it is called when the config is reloaded
+func (s *SSH) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	return err
+}
+
+func NewSSH(option SSHOption) (*SSH, error) {
+	server := net.JoinHostPort(option.Server, strconv.Itoa(option.Port))
+
+	var auth []ssh.AuthMethod
+	if option.PrivateKey != "" {
+		key := []byte(option.PrivateKey)
+		// private-key is either the pem content or a path of it
+		if !strings.Contains(option.PrivateKey, "PRIVATE KEY") {
+			var err error
+			key, err = ioutil.ReadFile(C.Path.Resolve(option.PrivateKey))
+			if err != nil {
+				return nil, fmt.Errorf("ssh %s read private-key error: %w", server, err)
+			}
+		}
+
+		var signer ssh.Signer
+		var err error
+		if option.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(option.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssh %s parse private-key error: %w", server, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if option.Password != "" {
+		auth = append(auth, ssh.Password(option.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("ssh %s requires password or private-key", server)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case len(option.HostKey) != 0:
+		var err error
+		hostKeyCallback, err = pinnedHostKeys(option.HostKey)
+		if err != nil {
+			return nil, fmt.Errorf("ssh %s host-key error: %w", server, err)
+		}
+	case option.SkipHostKeyVerify:
+		log.Warnln("[SSH] %s skips the host key verification, the connections are open to man-in-the-middle attacks", option.Name)
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("ssh %s requires host-key or skip-host-key-verify", server)
+	}
+
+	return &SSH{
+		Base: &Base{
+			name: option.Name,
+			addr: server,
+			tp:   C.SSH,
+		},
+		config: &ssh.ClientConfig{
+			User:            option.UserName,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+	}, nil
+}
+
+// pinnedHostKeys accepts the host keys in authorized_keys format or their
+// SHA256 fingerprints
+func pinnedHostKeys(keys []string) (ssh.HostKeyCallback, error) {
+	fingerprints := map[string]bool{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "SHA256:") {
+			fingerprints[key] = true
+			continue
+		}
+
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		fingerprints[ssh.FingerprintSHA256(pub)] = true
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if !fingerprints[fingerprint] {
+			return fmt.Errorf("host key %s mismatch", fingerprint)
+		}
+		return nil
+	}, nil
+}
+
+// sshConn emulates the read deadline that ssh channels don't support, the
+// channel is closed once the deadline is exceeded
+type sshConn struct {
+	net.Conn
+	// client is the session owned by the conn, nil for a pooled one
+	client *ssh.Client
+	mux    sync.Mutex
+	timer  *time.Timer
+}
+
+func (sc *sshConn) Close() error {
+	err := sc.Conn.Close()
+	if sc.client != nil {
+		sc.client.Close()
+	}
+	return err
+}
+
+func (sc *sshConn) SetDeadline(t time.Time) error {
+	return sc.SetReadDeadline(t)
+}
+
+func (sc *sshConn) SetReadDeadline(t time.Time) error {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	if sc.timer != nil {
+		sc.timer.Stop()
+		sc.timer = nil
+	}
+	if !t.IsZero() {
+		sc.timer = time.AfterFunc(time.Until(t), func() { sc.Conn.Close() })
+	}
+	return nil
+}
+
+func (sc *sshConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,277 @@
+package outbound
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshServer is an in-process ssh server echoing direct-tcpip channels back,
+// prefixed with the requested address
+type sshServer struct {
+	l       net.Listener
+	hostKey ssh.Signer
+	config  *ssh.ServerConfig
+
+	mux   sync.Mutex
+	conns []net.Conn
+}
+
+func newSSHServer(t *testing.T, authorized ssh.PublicKey) *sshServer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	hostKey, err := ssh.NewSignerFromKey(key)
+	assert.Nil(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "clash" && string(password) == "password" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorized != nil && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &sshServer{l: l, hostKey: hostKey, config: config}
+	go s.serve()
+	return s
+}
+
+func (s *sshServer) serve() {
+	for {
+		c, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mux.Lock()
+		s.conns = append(s.conns, c)
+		s.mux.Unlock()
+
+		go func(c net.Conn) {
+			defer c.Close()
+			_, chans, reqs, err := ssh.NewServerConn(c, s.config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+
+			for newChannel := range chans {
+				var req struct {
+					Host     string
+					Port     uint32
+					OrigHost string
+					OrigPort uint32
+				}
+				if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &req) != nil {
+					newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+					continue
+				}
+				if req.Port == 0 {
+					newChannel.Reject(ssh.ConnectionFailed, "connection refused")
+					continue
+				}
+
+				ch, chReqs, err := newChannel.Accept()
+				if err != nil {
+					continue
+				}
+				go ssh.DiscardRequests(chReqs)
+				go func() {
+					defer ch.Close()
+					fmt.Fprintf(ch, "%s:%d|", req.Host, req.Port)
+					io.Copy(ch, ch)
+				}()
+			}
+		}(c)
+	}
+}
+
+// dropConns closes the established connections
+func (s *sshServer) dropConns() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+}
+
+func (s *sshServer) count() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.conns)
+}
+
+func (s *sshServer) option() SSHOption {
+	_, port, _ := net.SplitHostPort(s.l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SSHOption{
+		Name:     "ssh",
+		Server:   "127.0.0.1",
+		Port:     p,
+		UserName: "clash",
+	}
+}
+
+func testSSHEcho(t *testing.T, proxy C.Proxy, host string) {
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: host, DstPort: "443"}
+	c, err := proxy.Dial(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+	assert.Equal(t, C.Chain{"ssh"}, c.Chains())
+
+	msg := "hello ssh"
+	_, err = c.Write([]byte(msg))
+	assert.Nil(t, err)
+
+	expected := host + ":443|" + msg
+	buf := make([]byte, len(expected))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(buf))
+}
+
+func TestSSH_Password(t *testing.T) {
+	server := newSSHServer(t, nil)
+	defer server.l.Close()
+
+	option := server.option()
+	option.Password = "password"
+	option.HostKey = []string{string(ssh.MarshalAuthorizedKey(server.hostKey.PublicKey()))}
+	s, err := NewSSH(option)
+	if !assert.Nil(t, err) {
+		return
+	}
+	proxy := NewProxy(s)
+
+	// the channels share one connection
+	testSSHEcho(t, proxy, "example.org")
+	testSSHEcho(t, proxy, "example.com")
+	assert.Equal(t, 1, server.count())
+
+	// the target refused the connection, which doesn't break the pool
+	_, err = proxy.Dial(&C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "0"})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, server.count())
+}
+
+func TestSSH_PrivateKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	server := newSSHServer(t, pub)
+	defer server.l.Close()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	block, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", der, []byte("passphrase"), x509.PEMCipherAES256)
+	assert.Nil(t, err)
+
+	option := server.option()
+	option.PrivateKey = string(pem.EncodeToMemory(block))
+	option.PrivateKeyPassphrase = "passphrase"
+	option.HostKey = []string{ssh.FingerprintSHA256(server.hostKey.PublicKey())}
+	s, err := NewSSH(option)
+	if !assert.Nil(t, err) {
+		return
+	}
+	testSSHEcho(t, NewProxy(s), "example.org")
+
+	option.PrivateKeyPassphrase = "wrong"
+	_, err = NewSSH(option)
+	assert.NotNil(t, err)
+}
+
+func TestSSH_HostKeyRequired(t *testing.T) {
+	option := SSHOption{Name: "ssh", Server: "127.0.0.1", Port: 22, UserName: "clash", Password: "password"}
+	_, err := NewSSH(option)
+	assert.NotNil(t, err)
+
+	option.SkipHostKeyVerify = true
+	_, err = NewSSH(option)
+	assert.Nil(t, err)
+}
+
+func TestSSH_HostKeyMismatch(t *testing.T) {
+	server := newSSHServer(t, nil)
+	defer server.l.Close()
+
+	option := server.option()
+	option.Password = "password"
+	option.HostKey = []string{"SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}
+	s, err := NewSSH(option)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = NewProxy(s).Dial(&C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "443"})
+	assert.NotNil(t, err)
+}
+
+func TestSSH_Reconnect(t *testing.T) {
+	server := newSSHServer(t, nil)
+	defer server.l.Close()
+
+	option := server.option()
+	option.Password = "password"
+	option.SkipHostKeyVerify = true
+	s, err := NewSSH(option)
+	if !assert.Nil(t, err) {
+		return
+	}
+	proxy := NewProxy(s)
+
+	testSSHEcho(t, proxy, "example.org")
+	server.dropConns()
+	testSSHEcho(t, proxy, "example.org")
+	assert.Equal(t, 2, server.count())
+}
+
+func TestSSH_Close(t *testing.T) {
+	server := newSSHServer(t, nil)
+	defer server.l.Close()
+
+	option := server.option()
+	option.Password = "password"
+	option.SkipHostKeyVerify = true
+	s, err := NewSSH(option)
+	if !assert.Nil(t, err) {
+		return
+	}
+	proxy := NewProxy(s)
+
+	testSSHEcho(t, proxy, "example.org")
+	assert.Nil(t, s.Close())
+	assert.Nil(t, s.client)
+	assert.Nil(t, s.Close())
+
+	// the pool is refilled if the proxy is used again
+	testSSHEcho(t, proxy, "example.org")
+	assert.Equal(t, 2, server.count())
+}
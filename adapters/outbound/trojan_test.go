@@ -0,0 +1,199 @@
+package outbound
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Dreamacro/clash/component/socks5"
+	"github.com/Dreamacro/clash/component/trojan"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTLSListener listens on a random local port with a self-signed certificate
+func newTLSListener(t *testing.T) net.Listener {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"h2", "http/1.1"},
+	})
+	assert.Nil(t, err)
+	return l
+}
+
+type trojanRequest struct {
+	command byte
+	addr    string
+}
+
+// serveTrojan is a trojan server stub echoing everything back
+func serveTrojan(l net.Listener, password string, requests chan<- trojanRequest) {
+	hash := sha256.Sum224([]byte(password))
+	expected := hex.EncodeToString(hash[:])
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(c net.Conn) {
+			defer c.Close()
+
+			buf := make([]byte, 56+2+1)
+			if _, err := io.ReadFull(c, buf); err != nil || string(buf[:56]) != expected {
+				return
+			}
+			command := buf[58]
+
+			addr, err := socks5.ReadAddr(c, make([]byte, socks5.MaxAddrLen))
+			if err != nil {
+				return
+			}
+			if _, err := io.ReadFull(c, buf[:2]); err != nil {
+				return
+			}
+			requests <- trojanRequest{command, addr.String()}
+
+			if command == trojan.CommandTCP {
+				io.Copy(c, c)
+				return
+			}
+
+			payload := make([]byte, 8192)
+			for {
+				from, n, err := trojan.ReadPacket(c, payload)
+				if err != nil {
+					return
+				}
+				if _, err := trojan.WritePacket(c, socks5.ParseAddrToSocksAddr(from), payload[:n]); err != nil {
+					return
+				}
+			}
+		}(c)
+	}
+}
+
+// newTestTrojan starts a stub server accepting "password" and a client using password
+func newTestTrojan(t *testing.T, password string) (C.Proxy, chan trojanRequest, func()) {
+	l := newTLSListener(t)
+	requests := make(chan trojanRequest, 1)
+	go serveTrojan(l, "password", requests)
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	proxy := NewProxy(NewTrojan(TrojanOption{
+		Name:           "trojan",
+		Server:         "127.0.0.1",
+		Port:           p,
+		Password:       password,
+		SNI:            "example.com",
+		SkipCertVerify: true,
+		UDP:            true,
+	}))
+	return proxy, requests, func() { l.Close() }
+}
+
+func TestTrojan_TCP(t *testing.T) {
+	proxy, requests, closer := newTestTrojan(t, "password")
+	defer closer()
+
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "443"}
+	c, err := proxy.Dial(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+	assert.Equal(t, C.Chain{"trojan"}, c.Chains())
+
+	msg := []byte("hello trojan")
+	_, err = c.Write(msg)
+	assert.Nil(t, err)
+
+	req := <-requests
+	assert.Equal(t, trojan.CommandTCP, req.command)
+	assert.Equal(t, "example.org:443", req.addr)
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+}
+
+func TestTrojan_UDP(t *testing.T) {
+	proxy, requests, closer := newTestTrojan(t, "password")
+	defer closer()
+
+	metadata := &C.Metadata{NetWork: C.UDP, AddrType: C.AtypIPv4, DstIP: net.ParseIP("1.1.1.1"), DstPort: "53"}
+	pc, err := proxy.DialUDP(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer pc.Close()
+
+	req := <-requests
+	assert.Equal(t, trojan.CommandUDP, req.command)
+	assert.Equal(t, "1.1.1.1:53", req.addr)
+
+	msg := []byte("hello udp")
+	_, err = pc.WriteWithMetadata(msg, metadata)
+	assert.Nil(t, err)
+
+	buf := make([]byte, 1024)
+	n, addr, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf[:n])
+	assert.Equal(t, "1.1.1.1:53", addr.String())
+
+	target := &net.UDPAddr{IP: net.ParseIP("8.8.8.8"), Port: 53}
+	_, err = pc.WriteTo(bytes.Repeat([]byte{1}, 100), target)
+	assert.Nil(t, err)
+
+	n, addr, err = pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, target.String(), addr.String())
+}
+
+func TestTrojan_WrongPassword(t *testing.T) {
+	proxy, _, closer := newTestTrojan(t, "wrong")
+	defer closer()
+
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "443"}
+	c, err := proxy.Dial(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	c.Write([]byte("hello"))
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
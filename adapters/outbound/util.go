@@ -52,6 +52,49 @@ func urlToMetadata(rawURL string) (addr C.Metadata, err error) {
 	return
 }
 
+// SupportStream reports whether the adapter can stream over a given
+// connection, which is what a hop of a relay does. Groups are unwrapped when
+// dialing, so they are left to the proxy they select.
+func SupportStream(adapter C.ProxyAdapter) bool {
+	switch adapter.Type() {
+	case C.Direct, C.Reject, C.WireGuard, C.Relay:
+		return false
+	case C.Vmess:
+		if v, ok := adapter.(*Vmess); ok {
+			return !v.client.Multiplexed()
+		}
+	}
+	return true
+}
+
+// AddrToMetadata returns the metadata of a host:port address
+func AddrToMetadata(rawAddress string, network C.NetWork) (*C.Metadata, error) {
+	host, port, err := net.SplitHostPort(rawAddress)
+	if err != nil {
+		return nil, fmt.Errorf("addrToMetadata failed: %w", err)
+	}
+
+	metadata := &C.Metadata{
+		NetWork: network,
+		DstPort: port,
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		metadata.AddrType = C.AtypDomainName
+		metadata.Host = host
+	case ip.To4() != nil:
+		metadata.AddrType = C.AtypIPv4
+		metadata.DstIP = ip.To4()
+	default:
+		metadata.AddrType = C.AtypIPv6
+		metadata.DstIP = ip
+	}
+
+	return metadata, nil
+}
+
 func tcpKeepAlive(c net.Conn) {
 	if tcp, ok := c.(*net.TCPConn); ok {
 		tcp.SetKeepAlive(true)
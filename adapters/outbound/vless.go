@@ -0,0 +1,144 @@
+package outbound
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/Dreamacro/clash/component/resolver"
+	"github.com/Dreamacro/clash/component/vless"
+	"github.com/Dreamacro/clash/component/vmess"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type Vless struct {
+	*Base
+	client    *vless.Client
+	tlsConfig *tls.Config
+	wsConfig  *vmess.WebsocketConfig
+}
+
+type VlessOption struct {
+	Name           string            `proxy:"name"`
+	Server         string            `proxy:"server"`
+	Port           int               `proxy:"port"`
+	UUID           string            `proxy:"uuid"`
+	TLS            bool              `proxy:"tls,omitempty"`
+	UDP            bool              `proxy:"udp,omitempty"`
+	Network        string            `proxy:"network,omitempty"`
+	WSPath         string            `proxy:"ws-path,omitempty"`
+	WSHeaders      map[string]string `proxy:"ws-headers,omitempty"`
+	ServerName     string            `proxy:"servername,omitempty"`
+	SkipCertVerify bool              `proxy:"skip-cert-verify,omitempty"`
+}
+
+// streamConn sets up the transport and the vless request over c
+func (v *Vless) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
+	var err error
+	if v.wsConfig != nil {
+		c, err = vmess.NewWebsocketConn(c, v.wsConfig)
+	} else if v.tlsConfig != nil {
+		tlsConn := tls.Client(c, v.tlsConfig)
+		err = tlsConn.Handshake()
+		c = tlsConn
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.client.StreamConn(c, parseVmessAddr(metadata))
+}
+
+func (v *Vless) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	c, err := v.dialContext(ctx, v.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", v.addr, err)
+	}
+	c, err = v.StreamConn(c, metadata)
+	return newConn(c, v), err
+}
+
+func (v *Vless) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	// vless use stream-oriented udp like vmess, so clash needs a net.UDPAddr
+	if !metadata.Resolved() {
+		ip, err := resolver.ResolveIP(metadata.Host)
+		if err != nil {
+			return nil, errors.New("can't resolve ip")
+		}
+		metadata.DstIP = ip
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tcpTimeout)
+	defer cancel()
+	c, err := v.dialContext(ctx, v.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", v.addr, err)
+	}
+	c, err = v.StreamConn(c, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("new vless client error: %v", err)
+	}
+	return newPacketConn(&vmessPacketConn{Conn: c, rAddr: metadata.UDPAddr()}, v), nil
+}
+
+func NewVless(option VlessOption) (*Vless, error) {
+	client, err := vless.NewClient(option.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if option.Network != "" && option.Network != "ws" {
+		return nil, fmt.Errorf("Unknown network type: %s", option.Network)
+	}
+
+	server := net.JoinHostPort(option.Server, strconv.Itoa(option.Port))
+
+	var tlsConfig *tls.Config
+	if option.TLS {
+		tlsConfig = &tls.Config{
+			ServerName:         option.Server,
+			InsecureSkipVerify: option.SkipCertVerify,
+			ClientSessionCache: getClientSessionCache(),
+		}
+		if option.ServerName != "" {
+			tlsConfig.ServerName = option.ServerName
+		}
+	}
+
+	var wsConfig *vmess.WebsocketConfig
+	if option.Network == "ws" {
+		header := http.Header{}
+		for k, v := range option.WSHeaders {
+			header.Add(k, v)
+		}
+
+		if host := header.Get("Host"); host != "" && tlsConfig != nil && option.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+
+		wsConfig = &vmess.WebsocketConfig{
+			Host:      server,
+			Path:      option.WSPath,
+			Headers:   header,
+			TLS:       option.TLS,
+			TLSConfig: tlsConfig,
+		}
+	}
+
+	return &Vless{
+		Base: &Base{
+			name: option.Name,
+			addr: server,
+			tp:   C.Vless,
+			udp:  option.UDP,
+		},
+		client:    client,
+		tlsConfig: tlsConfig,
+		wsConfig:  wsConfig,
+	}, nil
+}
@@ -0,0 +1,224 @@
+package outbound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Dreamacro/clash/component/vless"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+const testVlessUUID = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+type vlessRequest struct {
+	command byte
+	port    uint16
+	atyp    byte
+	addr    []byte
+}
+
+// handleVless is a vless server stub echoing everything back
+func handleVless(rw io.ReadWriter, requests chan<- vlessRequest) {
+	buf := make([]byte, 1+16+1+1+2+1)
+	if _, err := io.ReadFull(rw, buf); err != nil {
+		return
+	}
+
+	id := uuid.FromStringOrNil(testVlessUUID)
+	if buf[0] != vless.Version || !bytes.Equal(buf[1:17], id.Bytes()) || buf[17] != 0 {
+		return
+	}
+
+	req := vlessRequest{
+		command: buf[18],
+		port:    binary.BigEndian.Uint16(buf[19:21]),
+		atyp:    buf[21],
+	}
+
+	var addrLen int
+	switch req.atyp {
+	case 1:
+		addrLen = net.IPv4len
+	case 3:
+		addrLen = net.IPv6len
+	default:
+		var l [1]byte
+		if _, err := io.ReadFull(rw, l[:]); err != nil {
+			return
+		}
+		req.addr = l[:]
+		addrLen = int(l[0])
+	}
+
+	addr := make([]byte, addrLen)
+	if _, err := io.ReadFull(rw, addr); err != nil {
+		return
+	}
+	req.addr = append(req.addr, addr...)
+	requests <- req
+
+	// response with no addons
+	rw.Write([]byte{vless.Version, 0})
+	io.Copy(rw, rw)
+}
+
+func serveVlessTLS(t *testing.T, requests chan<- vlessRequest) net.Listener {
+	l := newTLSListener(t)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handleVless(c, requests)
+			}()
+		}
+	}()
+	return l
+}
+
+// wsReadWriter turns the messages of a websocket connection into a stream
+type wsReadWriter struct {
+	conn   *websocket.Conn
+	reader io.Reader
+}
+
+func (rw *wsReadWriter) Read(b []byte) (int, error) {
+	for {
+		if rw.reader == nil {
+			_, reader, err := rw.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			rw.reader = reader
+		}
+
+		n, err := rw.reader.Read(b)
+		if err == io.EOF {
+			rw.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (rw *wsReadWriter) Write(b []byte) (int, error) {
+	return len(b), rw.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+func newTestVless(t *testing.T, addr string, option VlessOption) C.Proxy {
+	host, port, _ := net.SplitHostPort(addr)
+	option.Name = "vless"
+	option.Server = host
+	option.Port, _ = strconv.Atoi(port)
+	option.UUID = testVlessUUID
+	option.UDP = true
+
+	proxy, err := NewVless(option)
+	assert.Nil(t, err)
+	return NewProxy(proxy)
+}
+
+func testVlessTCP(t *testing.T, proxy C.Proxy, requests <-chan vlessRequest) {
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "443"}
+	c, err := proxy.Dial(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	req := <-requests
+	assert.Equal(t, vless.CommandTCP, req.command)
+	assert.Equal(t, uint16(443), req.port)
+	assert.Equal(t, append([]byte{byte(len("example.org"))}, "example.org"...), req.addr)
+
+	msg := []byte("hello vless")
+	_, err = c.Write(msg)
+	assert.Nil(t, err)
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+}
+
+func TestVless_TLS(t *testing.T) {
+	requests := make(chan vlessRequest, 1)
+	l := serveVlessTLS(t, requests)
+	defer l.Close()
+
+	proxy := newTestVless(t, l.Addr().String(), VlessOption{TLS: true, SkipCertVerify: true})
+	testVlessTCP(t, proxy, requests)
+}
+
+func TestVless_UDP(t *testing.T) {
+	requests := make(chan vlessRequest, 1)
+	l := serveVlessTLS(t, requests)
+	defer l.Close()
+
+	proxy := newTestVless(t, l.Addr().String(), VlessOption{TLS: true, SkipCertVerify: true})
+
+	metadata := &C.Metadata{NetWork: C.UDP, AddrType: C.AtypIPv4, DstIP: net.ParseIP("1.1.1.1"), DstPort: "53"}
+	pc, err := proxy.DialUDP(metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer pc.Close()
+
+	req := <-requests
+	assert.Equal(t, vless.CommandUDP, req.command)
+	assert.Equal(t, uint16(53), req.port)
+	assert.Equal(t, []byte{1, 1, 1, 1}, req.addr)
+
+	buf := make([]byte, 1024)
+	for _, msg := range [][]byte{[]byte("first"), bytes.Repeat([]byte{2}, 600)} {
+		_, err = pc.WriteWithMetadata(msg, metadata)
+		assert.Nil(t, err)
+
+		n, addr, err := pc.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.Equal(t, msg, buf[:n])
+		assert.Equal(t, "1.1.1.1:53", addr.String())
+	}
+}
+
+func TestVless_WebSocket(t *testing.T) {
+	requests := make(chan vlessRequest, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/vless" || r.Header.Get("X-Test") != "clash" {
+			http.NotFound(w, r)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handleVless(&wsReadWriter{conn: conn}, requests)
+	}))
+	defer server.Close()
+
+	proxy := newTestVless(t, server.Listener.Addr().String(), VlessOption{
+		Network:   "ws",
+		WSPath:    "/vless",
+		WSHeaders: map[string]string{"X-Test": "clash"},
+	})
+	testVlessTCP(t, proxy, requests)
+}
@@ -8,7 +8,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/Dreamacro/clash/component/dialer"
 	"github.com/Dreamacro/clash/component/resolver"
 	"github.com/Dreamacro/clash/component/vmess"
 	C "github.com/Dreamacro/clash/constant"
@@ -16,7 +15,6 @@ import (
 
 type Vmess struct {
 	*Base
-	server string
 	client *vmess.Client
 }
 
@@ -32,16 +30,50 @@ type VmessOption struct {
 	Network        string            `proxy:"network,omitempty"`
 	WSPath         string            `proxy:"ws-path,omitempty"`
 	WSHeaders      map[string]string `proxy:"ws-headers,omitempty"`
+	HTTP2Opts      HTTP2Options      `proxy:"h2-opts,omitempty"`
+	GrpcOpts       GrpcOptions       `proxy:"grpc-opts,omitempty"`
 	SkipCertVerify bool              `proxy:"skip-cert-verify,omitempty"`
 }
 
-func (v *Vmess) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
-	c, err := dialer.DialContext(ctx, "tcp", v.server)
+type HTTP2Options struct {
+	Host []string `proxy:"host,omitempty"`
+	Path string   `proxy:"path,omitempty"`
+}
+
+type GrpcOptions struct {
+	GrpcServiceName string `proxy:"grpc-service-name,omitempty"`
+}
+
+func (v *Vmess) dialServer(ctx context.Context) (net.Conn, error) {
+	c, err := v.dialContext(ctx, v.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error", v.addr)
+	}
+	return c, nil
+}
+
+// dial opens a vmess connection, h2 and grpc streams share one connection to the server
+func (v *Vmess) dial(ctx context.Context, metadata *C.Metadata) (net.Conn, error) {
+	if v.client.Multiplexed() {
+		return v.client.NewStream(ctx, v.dialServer, parseVmessAddr(metadata))
+	}
+
+	c, err := v.dialServer(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%s connect error", v.server)
+		return nil, err
+	}
+	return v.StreamConn(c, metadata)
+}
+
+func (v *Vmess) StreamConn(c net.Conn, metadata *C.Metadata) (net.Conn, error) {
+	if v.client.Multiplexed() {
+		return nil, errors.New("h2 and grpc network can't stream over a given connection")
 	}
-	tcpKeepAlive(c)
-	c, err = v.client.New(c, parseVmessAddr(metadata))
+	return v.client.New(c, parseVmessAddr(metadata))
+}
+
+func (v *Vmess) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	c, err := v.dial(ctx, metadata)
 	return newConn(c, v), err
 }
 
@@ -57,18 +89,18 @@ func (v *Vmess) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), tcpTimeout)
 	defer cancel()
-	c, err := dialer.DialContext(ctx, "tcp", v.server)
-	if err != nil {
-		return nil, fmt.Errorf("%s connect error", v.server)
-	}
-	tcpKeepAlive(c)
-	c, err = v.client.New(c, parseVmessAddr(metadata))
+	c, err := v.dial(ctx, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("new vmess client error: %v", err)
 	}
 	return newPacketConn(&vmessPacketConn{Conn: c, rAddr: metadata.UDPAddr()}, v), nil
 }
 
+// Close closes the shared connection of h2 and grpc, it is called when the config is reloaded
+func (v *Vmess) Close() error {
+	return v.client.Close()
+}
+
 func NewVmess(option VmessOption) (*Vmess, error) {
 	security := strings.ToLower(option.Cipher)
 	client, err := vmess.NewClient(vmess.Config{
@@ -81,6 +113,9 @@ func NewVmess(option VmessOption) (*Vmess, error) {
 		NetWork:          option.Network,
 		WebSocketPath:    option.WSPath,
 		WebSocketHeaders: option.WSHeaders,
+		HTTP2Hosts:       option.HTTP2Opts.Host,
+		HTTP2Path:        option.HTTP2Opts.Path,
+		GrpcServiceName:  option.GrpcOpts.GrpcServiceName,
 		SkipCertVerify:   option.SkipCertVerify,
 		SessionCache:     getClientSessionCache(),
 	})
@@ -91,10 +126,10 @@ func NewVmess(option VmessOption) (*Vmess, error) {
 	return &Vmess{
 		Base: &Base{
 			name: option.Name,
+			addr: net.JoinHostPort(option.Server, strconv.Itoa(option.Port)),
 			tp:   C.Vmess,
 			udp:  true,
 		},
-		server: net.JoinHostPort(option.Server, strconv.Itoa(option.Port)),
 		client: client,
 	}, nil
 }
@@ -0,0 +1,224 @@
+package outbound
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/Dreamacro/clash/component/resolver"
+	"github.com/Dreamacro/clash/component/wireguard"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type WireGuard struct {
+	*Base
+	config wireguard.Config
+
+	mux    sync.Mutex
+	device *wireguard.Device
+}
+
+type WireGuardOption struct {
+	Name         string   `proxy:"name"`
+	Server       string   `proxy:"server"`
+	Port         int      `proxy:"port"`
+	IP           string   `proxy:"ip,omitempty"`
+	IPv6         string   `proxy:"ipv6,omitempty"`
+	PrivateKey   string   `proxy:"private-key"`
+	PublicKey    string   `proxy:"public-key"`
+	PreSharedKey string   `proxy:"pre-shared-key,omitempty"`
+	AllowedIPs   []string `proxy:"allowed-ips,omitempty"`
+	Reserved     []int    `proxy:"reserved,omitempty"`
+	MTU          int      `proxy:"mtu,omitempty"`
+	UDP          bool     `proxy:"udp,omitempty"`
+}
+
+// getDevice brings the device up on first use, so the endpoint is resolved
+// when the proxy is actually used
+func (w *WireGuard) getDevice() (*wireguard.Device, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	// a device closed by a failure of its conn is brought up again
+	if w.device != nil {
+		select {
+		case <-w.device.Done():
+			w.device = nil
+		default:
+			return w.device, nil
+		}
+	}
+
+	endpoint, err := resolveUDPAddr("udp", w.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s resolve error: %w", w.addr, err)
+	}
+
+	pc, err := w.listenPacket(w.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	config := w.config
+	config.Endpoint = endpoint
+	device, err := wireguard.NewDevice(pc, config)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	go func() {
+		<-device.Done()
+		pc.Close()
+	}()
+	w.device = device
+	return device, nil
+}
+
+// Close shuts the device down, it is called when the config is reloaded
+func (w *WireGuard) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.device != nil {
+		w.device.Close()
+		w.device = nil
+	}
+	return nil
+}
+
+func (w *WireGuard) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	device, err := w.getDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := metadata.DstIP
+	if !metadata.Resolved() {
+		ip, err = resolver.ResolveIP(metadata.Host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	port, _ := strconv.Atoi(metadata.DstPort)
+
+	c, err := device.DialContext(ctx, ip, port)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", w.addr, err)
+	}
+	return newConn(c, w), nil
+}
+
+func (w *WireGuard) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	device, err := w.getDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := metadata.DstIP
+	if !metadata.Resolved() {
+		ip, err = resolver.ResolveIP(metadata.Host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pc, err := device.ListenPacket(ip.To4() == nil)
+	if err != nil {
+		return nil, err
+	}
+	return newPacketConn(&wgPacketConn{pc}, w), nil
+}
+
+func (w *WireGuard) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"type": w.Type().String(),
+	})
+}
+
+func NewWireGuard(option WireGuardOption) (*WireGuard, error) {
+	server := net.JoinHostPort(option.Server, strconv.Itoa(option.Port))
+
+	privateKey, err := wireguard.ParseKey(option.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("wireguard %s private-key error: %w", server, err)
+	}
+	publicKey, err := wireguard.ParseKey(option.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("wireguard %s public-key error: %w", server, err)
+	}
+
+	config := wireguard.Config{
+		PrivateKey: privateKey,
+		PeerPublic: publicKey,
+		MTU:        option.MTU,
+	}
+
+	if option.PreSharedKey != "" {
+		config.PresharedKey, err = wireguard.ParseKey(option.PreSharedKey)
+		if err != nil {
+			return nil, fmt.Errorf("wireguard %s pre-shared-key error: %w", server, err)
+		}
+	}
+
+	for _, addr := range []string{option.IP, option.IPv6} {
+		if addr == "" {
+			continue
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("wireguard %s invalid ip: %s", server, addr)
+		}
+		config.Addresses = append(config.Addresses, ip)
+	}
+	if len(config.Addresses) == 0 {
+		return nil, fmt.Errorf("wireguard %s requires ip or ipv6", server)
+	}
+
+	for _, cidr := range option.AllowedIPs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("wireguard %s allowed-ips error: %w", server, err)
+		}
+		config.AllowedIPs = append(config.AllowedIPs, ipnet)
+	}
+
+	if len(option.Reserved) != 0 {
+		if len(option.Reserved) != len(config.Reserved) {
+			return nil, fmt.Errorf("wireguard %s reserved must be 3 bytes", server)
+		}
+		for i, b := range option.Reserved {
+			if b < 0 || b > 255 {
+				return nil, fmt.Errorf("wireguard %s invalid reserved byte: %d", server, b)
+			}
+			config.Reserved[i] = byte(b)
+		}
+	}
+
+	return &WireGuard{
+		Base: &Base{
+			name: option.Name,
+			addr: server,
+			tp:   C.WireGuard,
+			udp:  option.UDP,
+		},
+		config: config,
+	}, nil
+}
+
+type wgPacketConn struct {
+	net.PacketConn
+}
+
+func (wpc *wgPacketConn) WriteWithMetadata(p []byte, metadata *C.Metadata) (n int, err error) {
+	if !metadata.Resolved() {
+		ip, err := resolver.ResolveIP(metadata.Host)
+		if err != nil {
+			return 0, err
+		}
+		metadata.DstIP = ip
+	}
+	return wpc.WriteTo(p, metadata.UDPAddr())
+}
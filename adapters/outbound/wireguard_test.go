@@ -0,0 +1,75 @@
+package outbound
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dreamacro/clash/component/wireguard"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type udpPacketConn struct {
+	net.PacketConn
+}
+
+func (upc *udpPacketConn) WriteWithMetadata(p []byte, metadata *C.Metadata) (n int, err error) {
+	return upc.WriteTo(p, metadata.UDPAddr())
+}
+
+// packetDialer hands out local udp sockets, like the udp sessions of a dialer-proxy
+type packetDialer struct {
+	*Direct
+	conns chan net.PacketConn
+}
+
+func (d *packetDialer) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	d.conns <- pc
+	return newPacketConn(&udpPacketConn{pc}, d), nil
+}
+
+func TestWireGuard_Device(t *testing.T) {
+	w, err := NewWireGuard(WireGuardOption{
+		Name:       "wg",
+		Server:     "127.0.0.1",
+		Port:       51820,
+		IP:         "10.0.0.2",
+		PrivateKey: wireguard.NewPrivateKey().String(),
+		PublicKey:  wireguard.NewPrivateKey().PublicKey().String(),
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	d := &packetDialer{Direct: NewDirect(), conns: make(chan net.PacketConn, 2)}
+	w.SetDialer(NewProxy(d))
+
+	first, err := w.getDevice()
+	if !assert.Nil(t, err) {
+		return
+	}
+	same, _ := w.getDevice()
+	assert.Equal(t, first, same)
+
+	// a broken conn closes the device, the next use brings a new one up
+	(<-d.conns).Close()
+	select {
+	case <-first.Done():
+	case <-time.After(time.Second):
+		t.Fatal("device is not closed")
+	}
+	second, err := w.getDevice()
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, second)
+
+	// closing the adapter closes the device and its conn
+	assert.Nil(t, w.Close())
+	<-second.Done()
+	_, _, err = (<-d.conns).ReadFrom(make([]byte, 1))
+	assert.NotNil(t, err)
+}
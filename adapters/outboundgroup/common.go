@@ -3,6 +3,7 @@ package outboundgroup
 import (
 	"time"
 
+	"github.com/Dreamacro/clash/adapters/outbound"
 	"github.com/Dreamacro/clash/adapters/provider"
 	C "github.com/Dreamacro/clash/constant"
 )
@@ -11,10 +12,27 @@ const (
 	defaultGetProxiesDuration = time.Second * 5
 )
 
+// rejectProxy stands in for the proxies of a group when the filters leave none
+var rejectProxy = outbound.NewProxy(outbound.NewReject())
+
 func getProvidersProxies(providers []provider.ProxyProvider) []C.Proxy {
 	proxies := []C.Proxy{}
-	for _, provider := range providers {
-		proxies = append(proxies, provider.Proxies()...)
+	filtered := false
+	for _, pd := range providers {
+		proxies = append(proxies, pd.Proxies()...)
+		if _, ok := pd.(*filteredProvider); ok {
+			filtered = true
+		}
+	}
+	if len(proxies) == 0 && filtered {
+		proxies = append(proxies, rejectProxy)
 	}
 	return proxies
 }
+
+// touchProviders marks the proxies of a group as used when it carries traffic
+func touchProviders(providers []provider.ProxyProvider) {
+	for _, provider := range providers {
+		provider.Touch()
+	}
+}
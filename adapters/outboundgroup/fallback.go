@@ -22,6 +22,7 @@ func (f *Fallback) Now() string {
 }
 
 func (f *Fallback) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	touchProviders(f.providers)
 	proxy := f.findAliveProxy()
 	c, err := proxy.DialContext(ctx, metadata)
 	if err == nil {
@@ -31,6 +32,7 @@ func (f *Fallback) DialContext(ctx context.Context, metadata *C.Metadata) (C.Con
 }
 
 func (f *Fallback) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	touchProviders(f.providers)
 	proxy := f.findAliveProxy()
 	pc, err := proxy.DialUDP(metadata)
 	if err == nil {
@@ -56,6 +58,10 @@ func (f *Fallback) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (f *Fallback) Unwrap(metadata *C.Metadata) C.Proxy {
+	return f.findAliveProxy()
+}
+
 func (f *Fallback) GetProviders() []provider.ProxyProvider {
 	return f.providers
 }
@@ -81,7 +87,7 @@ func (f *Fallback) findAliveProxy() C.Proxy {
 
 func NewFallback(name string, providers []provider.ProxyProvider) *Fallback {
 	return &Fallback{
-		Base:      outbound.NewBase(name, C.Fallback, false),
+		Base:      outbound.NewBase(name, "", C.Fallback, false),
 		single:    singledo.NewSingle(defaultGetProxiesDuration),
 		providers: providers,
 	}
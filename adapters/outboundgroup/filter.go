@@ -0,0 +1,66 @@
+package outboundgroup
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/Dreamacro/clash/adapters/provider"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// filteredProvider only exposes the proxies of a provider whose names match
+// filter and don't match exclude, the set is filtered again when the
+// provider swaps its proxies
+type filteredProvider struct {
+	provider.ProxyProvider
+	filter  *regexp.Regexp
+	exclude *regexp.Regexp
+
+	mux     sync.Mutex
+	source  []C.Proxy
+	proxies []C.Proxy
+}
+
+func (fp *filteredProvider) Proxies() []C.Proxy {
+	source := fp.ProxyProvider.Proxies()
+
+	fp.mux.Lock()
+	defer fp.mux.Unlock()
+
+	if fp.proxies == nil || !sameProxies(source, fp.source) {
+		fp.source = source
+		fp.proxies = filterProxies(source, fp.filter, fp.exclude)
+	}
+	return fp.proxies
+}
+
+func filterProxies(proxies []C.Proxy, filter, exclude *regexp.Regexp) []C.Proxy {
+	filtered := []C.Proxy{}
+	for _, proxy := range proxies {
+		if filter != nil && !filter.MatchString(proxy.Name()) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(proxy.Name()) {
+			continue
+		}
+		filtered = append(filtered, proxy)
+	}
+	return filtered
+}
+
+// sameProxies reports whether a and b are the same slice, a provider replaces
+// the whole slice on update
+func sameProxies(a, b []C.Proxy) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
+
+func newFilteredProvider(pd provider.ProxyProvider, filter, exclude *regexp.Regexp) *filteredProvider {
+	return &filteredProvider{
+		ProxyProvider: pd,
+		filter:        filter,
+		exclude:       exclude,
+	}
+}
@@ -0,0 +1,90 @@
+package outboundgroup
+
+import (
+	"testing"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setProvider is a file provider whose proxies are swapped by the test
+type setProvider struct {
+	provider.ProxyProvider
+	proxies []C.Proxy
+}
+
+func (sp *setProvider) VehicleType() provider.VehicleType { return provider.File }
+func (sp *setProvider) Proxies() []C.Proxy                { return sp.proxies }
+
+func newNamedProxies(names ...string) []C.Proxy {
+	proxies := []C.Proxy{}
+	for _, name := range names {
+		proxies = append(proxies, outbound.NewProxy(outbound.NewHttp(outbound.HttpOption{Name: name, Server: "127.0.0.1", Port: 8080})))
+	}
+	return proxies
+}
+
+func proxyNames(proxies []C.Proxy) []string {
+	names := []string{}
+	for _, proxy := range proxies {
+		names = append(names, proxy.Name())
+	}
+	return names
+}
+
+func TestParseProxyGroup_Filter(t *testing.T) {
+	proxies := newNamedProxies("HK 01", "hk-02 premium", "Japan 01")
+	sp := &setProvider{ProxyProvider: newProvider(t, "sub", proxies...), proxies: proxies}
+	providersMap := map[string]provider.ProxyProvider{"sub": sp}
+
+	config := map[string]interface{}{
+		"name":           "hk",
+		"type":           "select",
+		"use":            []string{"sub"},
+		"filter":         "(?i)hong kong|hk",
+		"exclude-filter": "premium",
+	}
+	group, err := ParseProxyGroup(config, map[string]C.Proxy{}, providersMap)
+	if !assert.Nil(t, err) {
+		return
+	}
+	selector := group.(*Selector)
+	assert.Equal(t, []string{"HK 01"}, proxyNames(getProvidersProxies(selector.GetProviders())))
+
+	// the filters apply to the new set of the provider
+	sp.proxies = newNamedProxies("Hong Kong 03", "Japan 02")
+	assert.Equal(t, []string{"Hong Kong 03"}, proxyNames(getProvidersProxies(selector.GetProviders())))
+
+	// no match leaves the group rejecting
+	sp.proxies = newNamedProxies("Japan 03")
+	assert.Equal(t, []string{"REJECT"}, proxyNames(getProvidersProxies(selector.GetProviders())))
+}
+
+func TestParseProxyGroup_FilterMismatch(t *testing.T) {
+	proxies := newNamedProxies("Japan 01")
+	sp := &setProvider{ProxyProvider: newProvider(t, "sub", proxies...), proxies: proxies}
+	providersMap := map[string]provider.ProxyProvider{"sub": sp}
+
+	// the group loads and rejects until a proxy matches
+	config := map[string]interface{}{"name": "hk", "type": "select", "use": []string{"sub"}, "filter": "HK"}
+	group, err := ParseProxyGroup(config, map[string]C.Proxy{}, providersMap)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "REJECT", group.(*Selector).Now())
+	}
+
+	config["filter"] = "("
+	_, err = ParseProxyGroup(config, map[string]C.Proxy{}, providersMap)
+	assert.NotNil(t, err)
+}
+
+func TestGetProvidersProxies_Reject(t *testing.T) {
+	sp := &setProvider{ProxyProvider: newProvider(t, "sub", newNamedProxies("a")...)}
+
+	// only the filtered providers fall back to REJECT
+	assert.Empty(t, getProvidersProxies([]provider.ProxyProvider{sp}))
+	filtered := newFilteredProvider(sp, nil, nil)
+	assert.Equal(t, []string{"REJECT"}, proxyNames(getProvidersProxies([]provider.ProxyProvider{filtered})))
+}
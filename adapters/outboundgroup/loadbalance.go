@@ -3,10 +3,14 @@ package outboundgroup
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/Dreamacro/clash/adapters/outbound"
 	"github.com/Dreamacro/clash/adapters/provider"
+	"github.com/Dreamacro/clash/common/cache"
 	"github.com/Dreamacro/clash/common/murmur3"
 	"github.com/Dreamacro/clash/common/singledo"
 	C "github.com/Dreamacro/clash/constant"
@@ -14,11 +18,26 @@ import (
 	"golang.org/x/net/publicsuffix"
 )
 
+const (
+	// maxRetry is the number of hashes tried to find an alive proxy
+	maxRetry = 3
+
+	// stickySessionTTL is the idle time (second) before a sticky session expires
+	stickySessionTTL = 10 * 60
+	stickySessionMax = 4096
+)
+
+var errStrategy = errors.New("unsupported strategy")
+
+// strategyFn picks the proxy of metadata, proxies is never empty. The state of
+// the strategy only advances when commit is set, a peek leaves it as it is.
+type strategyFn = func(proxies []C.Proxy, metadata *C.Metadata, commit bool) C.Proxy
+
 type LoadBalance struct {
 	*outbound.Base
-	single    *singledo.Single
-	maxRetry  int
-	providers []provider.ProxyProvider
+	single     *singledo.Single
+	providers  []provider.ProxyProvider
+	strategyFn strategyFn
 }
 
 func getKey(metadata *C.Metadata) string {
@@ -52,47 +71,108 @@ func jumpHash(key uint64, buckets int32) int32 {
 	return int32(b)
 }
 
+// strategyConsistentHashing dials the requests of the same eTLD+1 on the same proxy
+func strategyConsistentHashing() strategyFn {
+	return func(proxies []C.Proxy, metadata *C.Metadata, commit bool) C.Proxy {
+		key := uint64(murmur3.Sum32([]byte(getKey(metadata))))
+		buckets := int32(len(proxies))
+		for i := 0; i < maxRetry; i, key = i+1, key+1 {
+			idx := jumpHash(key, buckets)
+			proxy := proxies[idx]
+			if proxy.Alive() {
+				return proxy
+			}
+		}
+
+		return proxies[0]
+	}
+}
+
+// strategyRoundRobin dials every request on the next alive proxy
+func strategyRoundRobin() strategyFn {
+	var counter uint32
+	return func(proxies []C.Proxy, metadata *C.Metadata, commit bool) C.Proxy {
+		length := uint32(len(proxies))
+		next := atomic.LoadUint32(&counter)
+		for i := uint32(0); i < length; i++ {
+			idx := next + i
+			if commit {
+				idx = atomic.AddUint32(&counter, 1) - 1
+			}
+			proxy := proxies[idx%length]
+			if proxy.Alive() {
+				return proxy
+			}
+		}
+
+		return proxies[0]
+	}
+}
+
+// strategyStickySessions spreads the requests like round-robin, but keeps the
+// requests from a source ip to the same eTLD+1 on the proxy of the first one
+// until the session is idle for stickySessionTTL
+func strategyStickySessions() strategyFn {
+	sessions := cache.NewLRUCache(
+		cache.WithAge(stickySessionTTL),
+		cache.WithUpdateAgeOnGet(),
+		cache.WithSize(stickySessionMax),
+	)
+	roundRobin := strategyRoundRobin()
+	return func(proxies []C.Proxy, metadata *C.Metadata, commit bool) C.Proxy {
+		key := metadata.SrcIP.String() + "-" + getKey(metadata)
+		get := sessions.Peek
+		if commit {
+			get = sessions.Get
+		}
+		if elm, exist := get(key); exist {
+			name := elm.(string)
+			for _, proxy := range proxies {
+				if proxy.Name() == name && proxy.Alive() {
+					return proxy
+				}
+			}
+		}
+
+		proxy := roundRobin(proxies, metadata, commit)
+		if commit {
+			sessions.Set(key, proxy.Name())
+		}
+		return proxy
+	}
+}
+
 func (lb *LoadBalance) DialContext(ctx context.Context, metadata *C.Metadata) (c C.Conn, err error) {
+	touchProviders(lb.providers)
 	defer func() {
 		if err == nil {
 			c.AppendToChains(lb)
 		}
 	}()
 
-	key := uint64(murmur3.Sum32([]byte(getKey(metadata))))
-	proxies := lb.proxies()
-	buckets := int32(len(proxies))
-	for i := 0; i < lb.maxRetry; i, key = i+1, key+1 {
-		idx := jumpHash(key, buckets)
-		proxy := proxies[idx]
-		if proxy.Alive() {
-			c, err = proxy.DialContext(ctx, metadata)
-			return
-		}
-	}
-	c, err = proxies[0].DialContext(ctx, metadata)
+	c, err = lb.pick(metadata).DialContext(ctx, metadata)
 	return
 }
 
 func (lb *LoadBalance) DialUDP(metadata *C.Metadata) (pc C.PacketConn, err error) {
+	touchProviders(lb.providers)
 	defer func() {
 		if err == nil {
 			pc.AppendToChains(lb)
 		}
 	}()
 
-	key := uint64(murmur3.Sum32([]byte(getKey(metadata))))
-	proxies := lb.proxies()
-	buckets := int32(len(proxies))
-	for i := 0; i < lb.maxRetry; i, key = i+1, key+1 {
-		idx := jumpHash(key, buckets)
-		proxy := proxies[idx]
-		if proxy.Alive() {
-			return proxy.DialUDP(metadata)
-		}
-	}
+	return lb.pick(metadata).DialUDP(metadata)
+}
+
+// pick selects the proxy to dial and advances the strategy
+func (lb *LoadBalance) pick(metadata *C.Metadata) C.Proxy {
+	return lb.strategyFn(lb.proxies(), metadata, true)
+}
 
-	return proxies[0].DialUDP(metadata)
+// Unwrap returns the proxy the next dial would use without advancing the strategy
+func (lb *LoadBalance) Unwrap(metadata *C.Metadata) C.Proxy {
+	return lb.strategyFn(lb.proxies(), metadata, false)
 }
 
 func (lb *LoadBalance) SupportUDP() bool {
@@ -122,11 +202,23 @@ func (lb *LoadBalance) MarshalJSON() ([]byte, error) {
 	})
 }
 
-func NewLoadBalance(name string, providers []provider.ProxyProvider) *LoadBalance {
-	return &LoadBalance{
-		Base:      outbound.NewBase(name, C.LoadBalance, false),
-		single:    singledo.NewSingle(defaultGetProxiesDuration),
-		maxRetry:  3,
-		providers: providers,
+func NewLoadBalance(name string, providers []provider.ProxyProvider, strategy string) (*LoadBalance, error) {
+	var fn strategyFn
+	switch strategy {
+	case "", "consistent-hashing":
+		fn = strategyConsistentHashing()
+	case "round-robin":
+		fn = strategyRoundRobin()
+	case "sticky-sessions":
+		fn = strategyStickySessions()
+	default:
+		return nil, fmt.Errorf("%w: %s", errStrategy, strategy)
 	}
+
+	return &LoadBalance{
+		Base:       outbound.NewBase(name, "", C.LoadBalance, false),
+		single:     singledo.NewSingle(defaultGetProxiesDuration),
+		providers:  providers,
+		strategyFn: fn,
+	}, nil
 }
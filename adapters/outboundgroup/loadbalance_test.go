@@ -0,0 +1,75 @@
+package outboundgroup
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoadBalance(t *testing.T, strategy string) *LoadBalance {
+	proxies := []C.Proxy{}
+	for _, name := range []string{"a", "b", "c"} {
+		proxies = append(proxies, outbound.NewProxy(outbound.NewHttp(outbound.HttpOption{Name: name, Server: "127.0.0.1", Port: 8080})))
+	}
+
+	lb, err := NewLoadBalance("lb", []provider.ProxyProvider{newProvider(t, "lb", proxies...)}, strategy)
+	assert.Nil(t, err)
+	return lb
+}
+
+func newMetadata(src, host string) *C.Metadata {
+	return &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, SrcIP: net.ParseIP(src), Host: host, DstPort: "443"}
+}
+
+func TestLoadBalance_ConsistentHashing(t *testing.T) {
+	for _, strategy := range []string{"", "consistent-hashing"} {
+		lb := newLoadBalance(t, strategy)
+		proxy := lb.Unwrap(newMetadata("10.0.0.1", "www.example.org"))
+		assert.Equal(t, proxy.Name(), lb.Unwrap(newMetadata("10.0.0.2", "api.example.org")).Name())
+	}
+}
+
+func TestLoadBalance_RoundRobin(t *testing.T) {
+	lb := newLoadBalance(t, "round-robin")
+
+	names := []string{}
+	for i := 0; i < 4; i++ {
+		names = append(names, lb.pick(newMetadata("10.0.0.1", "example.org")).Name())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, names)
+
+	// unwrapping shows the next proxy without moving on
+	assert.Equal(t, "b", lb.Unwrap(newMetadata("10.0.0.1", "example.org")).Name())
+	assert.Equal(t, "b", lb.Unwrap(newMetadata("10.0.0.1", "example.org")).Name())
+	assert.Equal(t, "b", lb.pick(newMetadata("10.0.0.1", "example.org")).Name())
+}
+
+func TestLoadBalance_StickySessions(t *testing.T) {
+	lb := newLoadBalance(t, "sticky-sessions")
+
+	// unwrapping doesn't open a session
+	assert.Equal(t, "a", lb.Unwrap(newMetadata("10.0.0.1", "example.com")).Name())
+
+	first := lb.pick(newMetadata("10.0.0.1", "www.example.org")).Name()
+	second := lb.pick(newMetadata("10.0.0.1", "example.com")).Name()
+	assert.Equal(t, "a", first)
+	assert.NotEqual(t, first, second)
+
+	// the same source to the same eTLD+1 sticks to its proxy
+	assert.Equal(t, first, lb.Unwrap(newMetadata("10.0.0.1", "api.example.org")).Name())
+	assert.Equal(t, first, lb.pick(newMetadata("10.0.0.1", "api.example.org")).Name())
+	assert.Equal(t, second, lb.pick(newMetadata("10.0.0.1", "example.com")).Name())
+
+	// another source gets another session
+	assert.NotEqual(t, first, lb.pick(newMetadata("10.0.0.2", "www.example.org")).Name())
+}
+
+func TestLoadBalance_UnsupportedStrategy(t *testing.T) {
+	_, err := NewLoadBalance("lb", nil, "random")
+	assert.NotNil(t, err)
+}
@@ -3,10 +3,13 @@ package outboundgroup
 import (
 	"errors"
 	"fmt"
+	"regexp"
 
+	"github.com/Dreamacro/clash/adapters/outbound"
 	"github.com/Dreamacro/clash/adapters/provider"
 	"github.com/Dreamacro/clash/common/structure"
 	C "github.com/Dreamacro/clash/constant"
+	"github.com/Dreamacro/clash/log"
 )
 
 var (
@@ -19,12 +22,17 @@ var (
 )
 
 type GroupCommonOption struct {
-	Name     string   `group:"name"`
-	Type     string   `group:"type"`
-	Proxies  []string `group:"proxies,omitempty"`
-	Use      []string `group:"use,omitempty"`
-	URL      string   `group:"url,omitempty"`
-	Interval int      `group:"interval,omitempty"`
+	Name          string   `group:"name"`
+	Type          string   `group:"type"`
+	Proxies       []string `group:"proxies,omitempty"`
+	Use           []string `group:"use,omitempty"`
+	URL           string   `group:"url,omitempty"`
+	Interval      int      `group:"interval,omitempty"`
+	Lazy          bool     `group:"lazy,omitempty"`
+	Tolerance     int      `group:"tolerance,omitempty"`
+	Strategy      string   `group:"strategy,omitempty"`
+	Filter        string   `group:"filter,omitempty"`
+	ExcludeFilter string   `group:"exclude-filter,omitempty"`
 }
 
 func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy, providersMap map[string]provider.ProxyProvider) (C.ProxyAdapter, error) {
@@ -55,7 +63,7 @@ func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy,
 
 		// if Use not empty, drop health check options
 		if len(groupOption.Use) != 0 {
-			hc := provider.NewHealthCheck(ps, "", 0)
+			hc := provider.NewHealthCheck(ps, "", 0, false)
 			pd, err := provider.NewCompatibleProvider(groupName, ps, hc)
 			if err != nil {
 				return nil, err
@@ -63,9 +71,9 @@ func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy,
 
 			providers = append(providers, pd)
 		} else {
-			// select don't need health check
-			if groupOption.Type == "select" {
-				hc := provider.NewHealthCheck(ps, "", 0)
+			// select and relay don't need health check
+			if groupOption.Type == "select" || groupOption.Type == "relay" {
+				hc := provider.NewHealthCheck(ps, "", 0, false)
 				pd, err := provider.NewCompatibleProvider(groupName, ps, hc)
 				if err != nil {
 					return nil, err
@@ -78,7 +86,7 @@ func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy,
 					return nil, errMissHealthCheck
 				}
 
-				hc := provider.NewHealthCheck(ps, groupOption.URL, uint(groupOption.Interval))
+				hc := provider.NewHealthCheck(ps, groupOption.URL, uint(groupOption.Interval), groupOption.Lazy)
 				pd, err := provider.NewCompatibleProvider(groupName, ps, hc)
 				if err != nil {
 					return nil, err
@@ -95,19 +103,35 @@ func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy,
 		if err != nil {
 			return nil, err
 		}
+
+		if groupOption.Filter != "" || groupOption.ExcludeFilter != "" {
+			list, err = filterProviders(groupName, list, groupOption.Filter, groupOption.ExcludeFilter)
+			if err != nil {
+				return nil, err
+			}
+		}
 		providers = append(providers, list...)
 	}
 
 	var group C.ProxyAdapter
 	switch groupOption.Type {
 	case "url-test":
-		group = NewURLTest(groupName, providers)
+		group = NewURLTest(groupName, providers, uint16(groupOption.Tolerance))
 	case "select":
 		group = NewSelector(groupName, providers)
 	case "fallback":
 		group = NewFallback(groupName, providers)
 	case "load-balance":
-		group = NewLoadBalance(groupName, providers)
+		lb, err := NewLoadBalance(groupName, providers, groupOption.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		group = lb
+	case "relay":
+		if err := checkRelayHops(providers); err != nil {
+			return nil, err
+		}
+		group = NewRelay(groupName, providers)
 	default:
 		return nil, fmt.Errorf("%w: %s", errType, groupOption.Type)
 	}
@@ -115,6 +139,22 @@ func ParseProxyGroup(config map[string]interface{}, proxyMap map[string]C.Proxy,
 	return group, nil
 }
 
+// checkRelayHops rejects the proxies which can't stream over the previous hop
+func checkRelayHops(providers []provider.ProxyProvider) error {
+	for _, pd := range providers {
+		for _, proxy := range pd.Proxies() {
+			p, ok := proxy.(*outbound.Proxy)
+			if !ok {
+				continue
+			}
+			if !outbound.SupportStream(p.ProxyAdapter) {
+				return fmt.Errorf("%s can't be a hop of relay: %s doesn't support streaming", proxy.Name(), proxy.Type())
+			}
+		}
+	}
+	return nil
+}
+
 func getProxies(mapping map[string]C.Proxy, list []string) ([]C.Proxy, error) {
 	var ps []C.Proxy
 	for _, name := range list {
@@ -142,3 +182,35 @@ func getProviders(mapping map[string]provider.ProxyProvider, list []string) ([]p
 	}
 	return ps, nil
 }
+
+// filterProviders wraps the providers of `use` with the filters, a group left
+// without proxies rejects the requests until the providers update
+func filterProviders(groupName string, providers []provider.ProxyProvider, filter, exclude string) ([]provider.ProxyProvider, error) {
+	var filterReg, excludeReg *regexp.Regexp
+	var err error
+	if filter != "" {
+		if filterReg, err = regexp.Compile(filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	if exclude != "" {
+		if excludeReg, err = regexp.Compile(exclude); err != nil {
+			return nil, fmt.Errorf("invalid exclude-filter: %w", err)
+		}
+	}
+
+	matched := false
+	list := []provider.ProxyProvider{}
+	for _, pd := range providers {
+		filtered := newFilteredProvider(pd, filterReg, excludeReg)
+		if len(filtered.Proxies()) != 0 {
+			matched = true
+		}
+		list = append(list, filtered)
+	}
+
+	if !matched {
+		log.Warnln("[ProxyGroup] %s: no proxy of `use` matches the filters", groupName)
+	}
+	return list, nil
+}
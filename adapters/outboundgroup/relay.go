@@ -0,0 +1,129 @@
+package outboundgroup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	"github.com/Dreamacro/clash/common/singledo"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type Relay struct {
+	*outbound.Base
+	single    *singledo.Single
+	providers []provider.ProxyProvider
+}
+
+func (r *Relay) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	touchProviders(r.providers)
+
+	proxies := r.proxies(metadata)
+	if len(proxies) == 0 {
+		return nil, errors.New("proxy does not exist")
+	}
+
+	first := proxies[0]
+	if len(proxies) == 1 {
+		c, err := first.DialContext(ctx, metadata)
+		if err == nil {
+			c.AppendToChains(r)
+		}
+		return c, err
+	}
+
+	c, err := dialServer(ctx, first)
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", first.Addr(), err)
+	}
+
+	// every hop streams to the server of the next one over the previous hop
+	for i, proxy := range proxies {
+		target := metadata
+		if i != len(proxies)-1 {
+			target, err = outbound.AddrToMetadata(proxies[i+1].Addr(), C.TCP)
+			if err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+
+		next, err := proxy.StreamConn(c, target)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("%s relay to %s error: %w", proxy.Name(), target.RemoteAddress(), err)
+		}
+		c = next
+	}
+
+	// the exit hop comes first like a proxy in a group
+	conn := outbound.NewConn(c, proxies[len(proxies)-1])
+	for i := len(proxies) - 2; i >= 0; i-- {
+		conn.AppendToChains(proxies[i])
+	}
+	conn.AppendToChains(r)
+	return conn, nil
+}
+
+// dialServer dials the server of the first hop the way the hop itself does
+func dialServer(ctx context.Context, proxy C.Proxy) (net.Conn, error) {
+	if p, ok := proxy.(*outbound.Proxy); ok {
+		if sd, ok := p.ProxyAdapter.(interface {
+			DialServer(ctx context.Context) (net.Conn, error)
+		}); ok {
+			return sd.DialServer(ctx)
+		}
+	}
+	return nil, fmt.Errorf("%s has no server to dial", proxy.Name())
+}
+
+func (r *Relay) MarshalJSON() ([]byte, error) {
+	var all []string
+	for _, proxy := range r.rawProxies() {
+		all = append(all, proxy.Name())
+	}
+	return json.Marshal(map[string]interface{}{
+		"type": r.Type().String(),
+		"all":  all,
+	})
+}
+
+func (r *Relay) GetProviders() []provider.ProxyProvider {
+	return r.providers
+}
+
+func (r *Relay) rawProxies() []C.Proxy {
+	elm, _, _ := r.single.Do(func() (interface{}, error) {
+		return getProvidersProxies(r.providers), nil
+	})
+
+	return elm.([]C.Proxy)
+}
+
+// proxies returns the hops in order, a group is replaced by the proxy it would use
+func (r *Relay) proxies(metadata *C.Metadata) []C.Proxy {
+	proxies := []C.Proxy{}
+	for _, proxy := range r.rawProxies() {
+		for {
+			unwrapped := proxy.Unwrap(metadata)
+			if unwrapped == nil {
+				break
+			}
+			proxy = unwrapped
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies
+}
+
+func NewRelay(name string, providers []provider.ProxyProvider) *Relay {
+	return &Relay{
+		Base:      outbound.NewBase(name, "", C.Relay, false),
+		single:    singledo.NewSingle(defaultGetProxiesDuration),
+		providers: providers,
+	}
+}
@@ -0,0 +1,179 @@
+package outboundgroup
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveConnect is a http proxy reporting the CONNECT targets
+func serveConnect(l net.Listener, targets chan<- string) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(c net.Conn) {
+			defer c.Close()
+			req, err := http.ReadRequest(bufio.NewReader(c))
+			if err != nil || req.Method != http.MethodConnect {
+				return
+			}
+			targets <- req.Host
+
+			remote, err := net.Dial("tcp", req.Host)
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+			io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+			go io.Copy(remote, c)
+			io.Copy(c, remote)
+		}(c)
+	}
+}
+
+func newHTTPHop(t *testing.T, name string) (C.Proxy, chan string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	targets := make(chan string, 1)
+	go serveConnect(l, targets)
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	proxy := outbound.NewProxy(outbound.NewHttp(outbound.HttpOption{Name: name, Server: "127.0.0.1", Port: p}))
+	return proxy, targets, func() { l.Close() }
+}
+
+func newProvider(t *testing.T, name string, proxies ...C.Proxy) provider.ProxyProvider {
+	pd, err := provider.NewCompatibleProvider(name, proxies, provider.NewHealthCheck(proxies, "", 0, false))
+	assert.Nil(t, err)
+	return pd
+}
+
+func TestRelay(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+
+	first, firstTargets, closeFirst := newHTTPHop(t, "first")
+	defer closeFirst()
+	second, secondTargets, closeSecond := newHTTPHop(t, "second")
+	defer closeSecond()
+
+	// a group member is replaced by its selected proxy
+	selector := NewSelector("select", []provider.ProxyProvider{newProvider(t, "select", second)})
+	pd := newProvider(t, "relay", first, outbound.NewProxy(selector))
+	relay := NewRelay("relay", []provider.ProxyProvider{pd})
+
+	_, port, _ := net.SplitHostPort(echo.Addr().String())
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypIPv4, DstIP: net.ParseIP("127.0.0.1").To4(), DstPort: port}
+	c, err := relay.DialContext(context.Background(), metadata)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.Equal(t, C.Chain{"second", "first", "relay"}, c.Chains())
+	assert.Equal(t, second.Addr(), <-firstTargets)
+	assert.Equal(t, echo.Addr().String(), <-secondTargets)
+
+	msg := []byte("hello relay")
+	_, err = c.Write(msg)
+	assert.Nil(t, err)
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+}
+
+func TestRelay_FirstHopDialer(t *testing.T) {
+	front, frontTargets, closeFront := newHTTPHop(t, "front")
+	defer closeFront()
+	first, _, closeFirst := newHTTPHop(t, "first")
+	defer closeFirst()
+	second, _, closeSecond := newHTTPHop(t, "second")
+	defer closeSecond()
+
+	// the first hop reaches its server through its dialer-proxy
+	first.(*outbound.Proxy).ProxyAdapter.(*outbound.Http).SetDialer(front)
+	relay := NewRelay("relay", []provider.ProxyProvider{newProvider(t, "relay", first, second)})
+
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "80"}
+	if c, err := relay.DialContext(context.Background(), metadata); err == nil {
+		c.Close()
+	}
+	assert.Equal(t, first.Addr(), <-frontTargets)
+}
+
+func TestRelay_Unsupported(t *testing.T) {
+	first, _, closeFirst := newHTTPHop(t, "first")
+	defer closeFirst()
+
+	// direct has no server to relay through
+	pd := newProvider(t, "relay", first, outbound.NewProxy(outbound.NewDirect()))
+	relay := NewRelay("relay", []provider.ProxyProvider{pd})
+
+	metadata := &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "example.org", DstPort: "80"}
+	_, err := relay.DialContext(context.Background(), metadata)
+	assert.NotNil(t, err)
+}
+
+func TestParseProxyGroup_Relay(t *testing.T) {
+	newHop := func(name string) C.Proxy {
+		return outbound.NewProxy(outbound.NewHttp(outbound.HttpOption{Name: name, Server: "127.0.0.1", Port: 8080}))
+	}
+	grpc, err := outbound.NewVmess(outbound.VmessOption{
+		Name:    "grpc",
+		Server:  "127.0.0.1",
+		Port:    443,
+		UUID:    "b831381d-6324-4d53-ad4f-8cda48b30811",
+		Cipher:  "auto",
+		TLS:     true,
+		Network: "grpc",
+	})
+	assert.Nil(t, err)
+
+	proxies := map[string]C.Proxy{
+		"a":      newHop("a"),
+		"b":      newHop("b"),
+		"direct": outbound.NewProxy(outbound.NewDirect()),
+		"grpc":   outbound.NewProxy(grpc),
+		"select": outbound.NewProxy(NewSelector("select", []provider.ProxyProvider{newProvider(t, "select", newHop("c"))})),
+	}
+
+	parse := func(hops ...string) error {
+		config := map[string]interface{}{"name": "relay", "type": "relay", "proxies": hops}
+		_, err := ParseProxyGroup(config, proxies, map[string]provider.ProxyProvider{})
+		return err
+	}
+
+	assert.Nil(t, parse("a", "b"))
+	assert.Nil(t, parse("a", "select"))
+	assert.NotNil(t, parse("a", "direct"))
+	assert.NotNil(t, parse("grpc", "a"))
+}
@@ -19,6 +19,7 @@ type Selector struct {
 }
 
 func (s *Selector) DialContext(ctx context.Context, metadata *C.Metadata) (C.Conn, error) {
+	touchProviders(s.providers)
 	c, err := s.selected.DialContext(ctx, metadata)
 	if err == nil {
 		c.AppendToChains(s)
@@ -27,6 +28,7 @@ func (s *Selector) DialContext(ctx context.Context, metadata *C.Metadata) (C.Con
 }
 
 func (s *Selector) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	touchProviders(s.providers)
 	pc, err := s.selected.DialUDP(metadata)
 	if err == nil {
 		pc.AppendToChains(s)
@@ -66,6 +68,10 @@ func (s *Selector) Set(name string) error {
 	return errors.New("Proxy does not exist")
 }
 
+func (s *Selector) Unwrap(metadata *C.Metadata) C.Proxy {
+	return s.selected
+}
+
 func (s *Selector) GetProviders() []provider.ProxyProvider {
 	return s.providers
 }
@@ -79,9 +85,9 @@ func (s *Selector) proxies() []C.Proxy {
 }
 
 func NewSelector(name string, providers []provider.ProxyProvider) *Selector {
-	selected := providers[0].Proxies()[0]
+	selected := getProvidersProxies(providers)[0]
 	return &Selector{
-		Base:      outbound.NewBase(name, C.Selector, false),
+		Base:      outbound.NewBase(name, "", C.Selector, false),
 		single:    singledo.NewSingle(defaultGetProxiesDuration),
 		providers: providers,
 		selected:  selected,
@@ -15,6 +15,8 @@ type URLTest struct {
 	*outbound.Base
 	single     *singledo.Single
 	fastSingle *singledo.Single
+	fastNode   C.Proxy
+	tolerance  uint16
 	providers  []provider.ProxyProvider
 }
 
@@ -23,6 +25,7 @@ func (u *URLTest) Now() string {
 }
 
 func (u *URLTest) DialContext(ctx context.Context, metadata *C.Metadata) (c C.Conn, err error) {
+	touchProviders(u.providers)
 	c, err = u.fast().DialContext(ctx, metadata)
 	if err == nil {
 		c.AppendToChains(u)
@@ -31,6 +34,7 @@ func (u *URLTest) DialContext(ctx context.Context, metadata *C.Metadata) (c C.Co
 }
 
 func (u *URLTest) DialUDP(metadata *C.Metadata) (C.PacketConn, error) {
+	touchProviders(u.providers)
 	pc, err := u.fast().DialUDP(metadata)
 	if err == nil {
 		pc.AppendToChains(u)
@@ -50,6 +54,10 @@ func (u *URLTest) proxies() []C.Proxy {
 	return elm.([]C.Proxy)
 }
 
+func (u *URLTest) Unwrap(metadata *C.Metadata) C.Proxy {
+	return u.fast()
+}
+
 func (u *URLTest) fast() C.Proxy {
 	elm, _, _ := u.fastSingle.Do(func() (interface{}, error) {
 		proxies := u.proxies()
@@ -66,7 +74,13 @@ func (u *URLTest) fast() C.Proxy {
 				min = delay
 			}
 		}
-		return fast, nil
+
+		// keep the current node unless the fastest one is faster by more than tolerance
+		if u.fastNode == nil || !u.fastNode.Alive() || !containsProxy(proxies, u.fastNode) ||
+			int(u.fastNode.LastDelay()) > int(min)+int(u.tolerance) {
+			u.fastNode = fast
+		}
+		return u.fastNode, nil
 	})
 
 	return elm.(C.Proxy)
@@ -88,11 +102,21 @@ func (u *URLTest) MarshalJSON() ([]byte, error) {
 	})
 }
 
-func NewURLTest(name string, providers []provider.ProxyProvider) *URLTest {
+func NewURLTest(name string, providers []provider.ProxyProvider, tolerance uint16) *URLTest {
 	return &URLTest{
-		Base:       outbound.NewBase(name, C.URLTest, false),
+		Base:       outbound.NewBase(name, "", C.URLTest, false),
 		single:     singledo.NewSingle(defaultGetProxiesDuration),
 		fastSingle: singledo.NewSingle(time.Second * 10),
+		tolerance:  tolerance,
 		providers:  providers,
 	}
 }
+
+func containsProxy(proxies []C.Proxy, target C.Proxy) bool {
+	for _, proxy := range proxies {
+		if proxy == target {
+			return true
+		}
+	}
+	return false
+}
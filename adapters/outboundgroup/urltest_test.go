@@ -0,0 +1,39 @@
+package outboundgroup
+
+import (
+	"testing"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	"github.com/Dreamacro/clash/common/singledo"
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// delayProxy is an alive proxy with a fixed delay
+type delayProxy struct {
+	C.Proxy
+	name  string
+	delay uint16
+}
+
+func (dp *delayProxy) Name() string      { return dp.name }
+func (dp *delayProxy) Alive() bool       { return true }
+func (dp *delayProxy) LastDelay() uint16 { return dp.delay }
+
+func TestURLTest_Tolerance(t *testing.T) {
+	a := &delayProxy{Proxy: outbound.NewProxy(outbound.NewDirect()), name: "a", delay: 100}
+	b := &delayProxy{Proxy: outbound.NewProxy(outbound.NewDirect()), name: "b", delay: 90}
+
+	u := NewURLTest("url-test", []provider.ProxyProvider{newProvider(t, "url-test", a, b)}, 20)
+	u.fastSingle = singledo.NewSingle(0)
+	assert.Equal(t, "b", u.Now())
+
+	// a is faster, but within the tolerance
+	a.delay, b.delay = 85, 100
+	assert.Equal(t, "b", u.Now())
+
+	a.delay = 70
+	assert.Equal(t, "a", u.Now())
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dreamacro/clash/log"
+)
+
+type parser = func([]byte) (interface{}, error)
+
+// fetcher loads the content of a provider from its vehicle, keeps a local copy
+// on disk and pulls it periodically when an interval is set
+type fetcher struct {
+	name      string
+	vehicle   Vehicle
+	updatedAt atomic.Value
+	ticker    *time.Ticker
+	done      chan struct{}
+	hash      [16]byte
+	parser    parser
+	onUpdate  func(interface{})
+}
+
+func (f *fetcher) Name() string {
+	return f.name
+}
+
+// UpdatedAt returns the last time the content was pulled, nil if it never was
+func (f *fetcher) UpdatedAt() *time.Time {
+	updatedAt, ok := f.updatedAt.Load().(time.Time)
+	if !ok {
+		return nil
+	}
+	return &updatedAt
+}
+
+func (f *fetcher) VehicleType() VehicleType {
+	return f.vehicle.Type()
+}
+
+func (f *fetcher) Initial() error {
+	var buf []byte
+	var err error
+	var isLocal bool
+	if stat, err := os.Stat(f.vehicle.Path()); err == nil {
+		buf, err = ioutil.ReadFile(f.vehicle.Path())
+		f.updatedAt.Store(stat.ModTime())
+		isLocal = true
+	} else {
+		buf, err = f.vehicle.Read()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	elm, err := f.parser(buf)
+	if err != nil {
+		if !isLocal {
+			return err
+		}
+
+		// parse local file error, fallback to remote
+		buf, err = f.vehicle.Read()
+		if err != nil {
+			return err
+		}
+
+		elm, err = f.parser(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ioutil.WriteFile(f.vehicle.Path(), buf, fileMode); err != nil {
+		return err
+	}
+
+	f.hash = md5.Sum(buf)
+	f.onUpdate(elm)
+
+	// pull automatically
+	if f.ticker != nil {
+		go f.pullLoop()
+	}
+
+	return nil
+}
+
+func (f *fetcher) Update() error {
+	return f.pull()
+}
+
+func (f *fetcher) Destroy() error {
+	if f.ticker != nil {
+		f.ticker.Stop()
+		f.done <- struct{}{}
+	}
+
+	return nil
+}
+
+func (f *fetcher) pullLoop() {
+	for {
+		select {
+		case <-f.ticker.C:
+			if err := f.pull(); err != nil {
+				log.Warnln("[Provider] %s pull error: %s", f.Name(), err.Error())
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (f *fetcher) pull() error {
+	buf, err := f.vehicle.Read()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	hash := md5.Sum(buf)
+	if bytes.Equal(f.hash[:], hash[:]) {
+		log.Debugln("[Provider] %s doesn't change", f.Name())
+		f.updatedAt.Store(now)
+		return nil
+	}
+
+	elm, err := f.parser(buf)
+	if err != nil {
+		return err
+	}
+	log.Infoln("[Provider] %s updated", f.Name())
+
+	if err := ioutil.WriteFile(f.vehicle.Path(), buf, fileMode); err != nil {
+		return err
+	}
+
+	f.updatedAt.Store(now)
+	f.hash = hash
+	f.onUpdate(elm)
+
+	return nil
+}
+
+func newFetcher(name string, interval time.Duration, vehicle Vehicle, parser parser, onUpdate func(interface{})) *fetcher {
+	var ticker *time.Ticker
+	if interval != 0 {
+		ticker = time.NewTicker(interval)
+	}
+
+	return &fetcher{
+		name:     name,
+		vehicle:  vehicle,
+		ticker:   ticker,
+		done:     make(chan struct{}, 1),
+		parser:   parser,
+		onUpdate: onUpdate,
+	}
+}
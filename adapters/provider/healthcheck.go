@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	C "github.com/Dreamacro/clash/constant"
@@ -21,7 +22,10 @@ type HealthCheck struct {
 	url      string
 	proxies  []C.Proxy
 	interval uint
-	done     chan struct{}
+	lazy     bool
+	// lastTouch is the unix time the proxies were last used, see touch
+	lastTouch int64
+	done      chan struct{}
 }
 
 func (hc *HealthCheck) process() {
@@ -31,7 +35,9 @@ func (hc *HealthCheck) process() {
 	for {
 		select {
 		case <-ticker.C:
-			hc.check()
+			if hc.shouldCheck(time.Now().Unix()) {
+				hc.check()
+			}
 		case <-hc.done:
 			ticker.Stop()
 			return
@@ -39,10 +45,20 @@ func (hc *HealthCheck) process() {
 	}
 }
 
+// shouldCheck reports whether the tick at now runs the check, a lazy health
+// check skips the ticks without traffic since the last one
+func (hc *HealthCheck) shouldCheck(now int64) bool {
+	return !hc.lazy || now-atomic.LoadInt64(&hc.lastTouch) < int64(hc.interval)
+}
+
 func (hc *HealthCheck) setProxy(proxies []C.Proxy) {
 	hc.proxies = proxies
 }
 
+func (hc *HealthCheck) touch() {
+	atomic.StoreInt64(&hc.lastTouch, time.Now().Unix())
+}
+
 func (hc *HealthCheck) auto() bool {
 	return hc.interval != 0
 }
@@ -61,7 +77,7 @@ func (hc *HealthCheck) close() {
 	hc.done <- struct{}{}
 }
 
-func NewHealthCheck(proxies []C.Proxy, url string, interval uint) *HealthCheck {
+func NewHealthCheck(proxies []C.Proxy, url string, interval uint, lazy bool) *HealthCheck {
 	if len(url) == 0 {
 		url = defaultURLTestURL
 	}
@@ -70,6 +86,7 @@ func NewHealthCheck(proxies []C.Proxy, url string, interval uint) *HealthCheck {
 		proxies:  proxies,
 		url:      url,
 		interval: interval,
+		lazy:     lazy,
 		done:     make(chan struct{}, 1),
 	}
 }
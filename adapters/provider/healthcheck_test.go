@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck_Lazy(t *testing.T) {
+	now := time.Now().Unix()
+
+	// an idle lazy check skips the tick
+	hc := NewHealthCheck(nil, "", 300, true)
+	assert.False(t, hc.shouldCheck(now))
+
+	// a touch within the interval runs it
+	hc.touch()
+	touched := atomic.LoadInt64(&hc.lastTouch)
+	assert.True(t, hc.shouldCheck(touched+299))
+	assert.False(t, hc.shouldCheck(touched+300))
+
+	// a check which isn't lazy runs every tick
+	assert.True(t, NewHealthCheck(nil, "", 300, false).shouldCheck(now))
+}
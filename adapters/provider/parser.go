@@ -11,13 +11,15 @@ import (
 )
 
 var (
-	errVehicleType = errors.New("unsupport vehicle type")
+	errVehicleType  = errors.New("unsupport vehicle type")
+	errBehaviorType = errors.New("unsupport behavior type")
 )
 
 type healthCheckSchema struct {
 	Enable   bool   `provider:"enable"`
 	URL      string `provider:"url"`
 	Interval int    `provider:"interval"`
+	Lazy     bool   `provider:"lazy,omitempty"`
 }
 
 type proxyProviderSchema struct {
@@ -40,20 +42,63 @@ func ParseProxyProvider(name string, mapping map[string]interface{}, baseDir str
 	if schema.HealthCheck.Enable {
 		hcInterval = uint(schema.HealthCheck.Interval)
 	}
-	hc := NewHealthCheck([]C.Proxy{}, schema.HealthCheck.URL, hcInterval)
+	hc := NewHealthCheck([]C.Proxy{}, schema.HealthCheck.URL, hcInterval, schema.HealthCheck.Lazy)
 
 	path := filepath.Join(baseDir, schema.Path)
+	vehicle, err := parseVehicle(schema.Type, schema.URL, path)
+	if err != nil {
+		return nil, err
+	}
 
-	var vehicle Vehicle
-	switch schema.Type {
-	case "file":
-		vehicle = NewFileVehicle(path)
-	case "http":
-		vehicle = NewHTTPVehicle(schema.URL, path)
+	interval := time.Duration(uint(schema.Interval)) * time.Second
+	return NewProxySetProvider(name, interval, vehicle, hc), nil
+}
+
+type ruleProviderSchema struct {
+	Type     string `provider:"type"`
+	Behavior string `provider:"behavior"`
+	Path     string `provider:"path"`
+	URL      string `provider:"url,omitempty"`
+	Interval int    `provider:"interval,omitempty"`
+}
+
+func ParseRuleProvider(name string, mapping map[string]interface{}, baseDir string) (RuleProvider, error) {
+	decoder := structure.NewDecoder(structure.Option{TagName: "provider", WeaklyTypedInput: true})
+
+	schema := &ruleProviderSchema{}
+	if err := decoder.Decode(mapping, schema); err != nil {
+		return nil, err
+	}
+
+	var behavior RuleBehavior
+	switch schema.Behavior {
+	case "domain":
+		behavior = Domain
+	case "ipcidr":
+		behavior = IPCIDR
+	case "classical":
+		behavior = Classical
 	default:
-		return nil, fmt.Errorf("%w: %s", errVehicleType, schema.Type)
+		return nil, fmt.Errorf("%w: %s", errBehaviorType, schema.Behavior)
+	}
+
+	path := filepath.Join(baseDir, schema.Path)
+	vehicle, err := parseVehicle(schema.Type, schema.URL, path)
+	if err != nil {
+		return nil, err
 	}
 
 	interval := time.Duration(uint(schema.Interval)) * time.Second
-	return NewProxySetProvider(name, interval, vehicle, hc), nil
+	return NewRuleSetProvider(name, behavior, interval, vehicle), nil
+}
+
+func parseVehicle(tp string, url string, path string) (Vehicle, error) {
+	switch tp {
+	case "file":
+		return NewFileVehicle(path), nil
+	case "http":
+		return NewHTTPVehicle(url, path), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errVehicleType, tp)
+	}
 }
@@ -1,13 +1,9 @@
 package provider
 
 import (
-	"bytes"
-	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"time"
 
 	"github.com/Dreamacro/clash/adapters/outbound"
@@ -57,6 +53,8 @@ type Provider interface {
 type ProxyProvider interface {
 	Provider
 	Proxies() []C.Proxy
+	// Touch marks the proxies as used, which keeps a lazy health check running
+	Touch()
 	HealthCheck()
 	Update() error
 }
@@ -66,13 +64,9 @@ type ProxySchema struct {
 }
 
 type ProxySetProvider struct {
-	name        string
-	vehicle     Vehicle
-	hash        [16]byte
+	*fetcher
 	proxies     []C.Proxy
 	healthCheck *HealthCheck
-	ticker      *time.Ticker
-	updatedAt   *time.Time
 }
 
 func (pp *ProxySetProvider) MarshalJSON() ([]byte, error) {
@@ -81,88 +75,25 @@ func (pp *ProxySetProvider) MarshalJSON() ([]byte, error) {
 		"type":        pp.Type().String(),
 		"vehicleType": pp.VehicleType().String(),
 		"proxies":     pp.Proxies(),
-		"updatedAt":   pp.updatedAt,
+		"updatedAt":   pp.UpdatedAt(),
 	})
 }
 
-func (pp *ProxySetProvider) Name() string {
-	return pp.name
-}
-
 func (pp *ProxySetProvider) Reload() error {
 	return nil
 }
 
-func (pp *ProxySetProvider) HealthCheck() {
-	pp.healthCheck.check()
+func (pp *ProxySetProvider) Touch() {
+	pp.healthCheck.touch()
 }
 
-func (pp *ProxySetProvider) Update() error {
-	return pp.pull()
+func (pp *ProxySetProvider) HealthCheck() {
+	pp.healthCheck.check()
 }
 
 func (pp *ProxySetProvider) Destroy() error {
 	pp.healthCheck.close()
-
-	if pp.ticker != nil {
-		pp.ticker.Stop()
-	}
-
-	return nil
-}
-
-func (pp *ProxySetProvider) Initial() error {
-	var buf []byte
-	var err error
-	var isLocal bool
-	if stat, err := os.Stat(pp.vehicle.Path()); err == nil {
-		buf, err = ioutil.ReadFile(pp.vehicle.Path())
-		modTime := stat.ModTime()
-		pp.updatedAt = &modTime
-		isLocal = true
-	} else {
-		buf, err = pp.vehicle.Read()
-	}
-
-	if err != nil {
-		return err
-	}
-
-	proxies, err := pp.parse(buf)
-	if err != nil {
-		if !isLocal {
-			return err
-		}
-
-		// parse local file error, fallback to remote
-		buf, err = pp.vehicle.Read()
-		if err != nil {
-			return err
-		}
-
-		proxies, err = pp.parse(buf)
-		if err != nil {
-			return err
-		}
-	}
-
-	if err := ioutil.WriteFile(pp.vehicle.Path(), buf, fileMode); err != nil {
-		return err
-	}
-
-	pp.hash = md5.Sum(buf)
-	pp.setProxies(proxies)
-
-	// pull proxies automatically
-	if pp.ticker != nil {
-		go pp.pullLoop()
-	}
-
-	return nil
-}
-
-func (pp *ProxySetProvider) VehicleType() VehicleType {
-	return pp.vehicle.Type()
+	return pp.fetcher.Destroy()
 }
 
 func (pp *ProxySetProvider) Type() ProviderType {
@@ -173,60 +104,35 @@ func (pp *ProxySetProvider) Proxies() []C.Proxy {
 	return pp.proxies
 }
 
-func (pp *ProxySetProvider) pullLoop() {
-	for range pp.ticker.C {
-		if err := pp.pull(); err != nil {
-			log.Warnln("[Provider] %s pull error: %s", pp.Name(), err.Error())
-		}
-	}
-}
-
-func (pp *ProxySetProvider) pull() error {
-	buf, err := pp.vehicle.Read()
-	if err != nil {
-		return err
-	}
-
-	now := time.Now()
-	hash := md5.Sum(buf)
-	if bytes.Equal(pp.hash[:], hash[:]) {
-		log.Debugln("[Provider] %s's proxies doesn't change", pp.Name())
-		pp.updatedAt = &now
-		return nil
-	}
-
-	proxies, err := pp.parse(buf)
-	if err != nil {
-		return err
-	}
-	log.Infoln("[Provider] %s's proxies update", pp.Name())
-
-	if err := ioutil.WriteFile(pp.vehicle.Path(), buf, fileMode); err != nil {
-		return err
-	}
-
-	pp.updatedAt = &now
-	pp.hash = hash
-	pp.setProxies(proxies)
-
-	return nil
-}
-
-func (pp *ProxySetProvider) parse(buf []byte) ([]C.Proxy, error) {
+func proxiesParse(buf []byte) (interface{}, error) {
 	schema := &ProxySchema{}
 
-	if err := yaml.Unmarshal(buf, schema); err != nil {
-		return nil, err
-	}
+	// subscriptions are lists of share uris, base64 encoded or not
+	format, decoded := detectFormat(buf)
+	if format == formatURIList {
+		mappings, err := parseURIList(decoded)
+		if err != nil {
+			return nil, err
+		}
+		schema.Proxies = mappings
+	} else {
+		if err := yaml.Unmarshal(buf, schema); err != nil {
+			return nil, err
+		}
 
-	if schema.Proxies == nil {
-		return nil, errors.New("File must have a `proxies` field")
+		if schema.Proxies == nil {
+			return nil, errors.New("File must have a `proxies` field")
+		}
 	}
 
 	proxies := []C.Proxy{}
 	for idx, mapping := range schema.Proxies {
 		proxy, err := outbound.ParseProxy(mapping)
-		if err != nil {
+		if err != nil && format == formatURIList {
+			// a bad line of a subscription doesn't fail the others
+			log.Warnln("Proxy %d: %s", idx, err.Error())
+			continue
+		} else if err != nil {
 			return nil, fmt.Errorf("Proxy %d error: %w", idx, err)
 		}
 		proxies = append(proxies, proxy)
@@ -246,22 +152,21 @@ func (pp *ProxySetProvider) setProxies(proxies []C.Proxy) {
 }
 
 func NewProxySetProvider(name string, interval time.Duration, vehicle Vehicle, hc *HealthCheck) *ProxySetProvider {
-	var ticker *time.Ticker
-	if interval != 0 {
-		ticker = time.NewTicker(interval)
-	}
-
 	if hc.auto() {
 		go hc.process()
 	}
 
-	return &ProxySetProvider{
-		name:        name,
-		vehicle:     vehicle,
+	pd := &ProxySetProvider{
 		proxies:     []C.Proxy{},
 		healthCheck: hc,
-		ticker:      ticker,
 	}
+
+	onUpdate := func(elm interface{}) {
+		pd.setProxies(elm.([]C.Proxy))
+	}
+
+	pd.fetcher = newFetcher(name, interval, vehicle, proxiesParse, onUpdate)
+	return pd
 }
 
 type CompatibleProvider struct {
@@ -292,6 +197,10 @@ func (cp *CompatibleProvider) Destroy() error {
 	return nil
 }
 
+func (cp *CompatibleProvider) Touch() {
+	cp.healthCheck.touch()
+}
+
 func (cp *CompatibleProvider) HealthCheck() {
 	cp.healthCheck.check()
 }
@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	cidr "github.com/Dreamacro/clash/component/cidr-trie"
+	trie "github.com/Dreamacro/clash/component/domain-trie"
+	C "github.com/Dreamacro/clash/constant"
+	R "github.com/Dreamacro/clash/rules"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rule Behavior
+const (
+	Domain RuleBehavior = iota
+	IPCIDR
+	Classical
+)
+
+// RuleBehavior defined
+type RuleBehavior int
+
+func (rb RuleBehavior) String() string {
+	switch rb {
+	case Domain:
+		return "Domain"
+	case IPCIDR:
+		return "IPCIDR"
+	case Classical:
+		return "Classical"
+	default:
+		return "Unknown"
+	}
+}
+
+// RuleProvider interface
+type RuleProvider interface {
+	Provider
+	Behavior() RuleBehavior
+	Match(metadata *C.Metadata) bool
+	ShouldResolveIP() bool
+	ShouldFindProcess() bool
+	Update() error
+}
+
+type RuleSchema struct {
+	Payload []string `yaml:"payload"`
+}
+
+// ruleStrategy is the compiled content of a rule provider
+type ruleStrategy interface {
+	Match(metadata *C.Metadata) bool
+	ShouldResolveIP() bool
+	ShouldFindProcess() bool
+	Count() int
+}
+
+type domainStrategy struct {
+	domains *trie.Trie
+	count   int
+}
+
+func (ds *domainStrategy) Match(metadata *C.Metadata) bool {
+	if metadata.AddrType != C.AtypDomainName {
+		return false
+	}
+	return ds.domains.SearchSuffix(metadata.Host) != nil
+}
+
+func (ds *domainStrategy) ShouldResolveIP() bool {
+	return false
+}
+
+func (ds *domainStrategy) ShouldFindProcess() bool {
+	return false
+}
+
+func (ds *domainStrategy) Count() int {
+	return ds.count
+}
+
+type ipcidrStrategy struct {
+	ipnets *cidr.Trie
+	count  int
+}
+
+func (is *ipcidrStrategy) Match(metadata *C.Metadata) bool {
+	ip := metadata.DstIP
+	return ip != nil && is.ipnets.Contains(ip)
+}
+
+func (is *ipcidrStrategy) ShouldResolveIP() bool {
+	return true
+}
+
+func (is *ipcidrStrategy) ShouldFindProcess() bool {
+	return false
+}
+
+func (is *ipcidrStrategy) Count() int {
+	return is.count
+}
+
+type classicalStrategy struct {
+	rules             []C.Rule
+	shouldResolveIP   bool
+	shouldFindProcess bool
+}
+
+func (cs *classicalStrategy) Match(metadata *C.Metadata) bool {
+	for _, rule := range cs.rules {
+		if rule.Match(metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *classicalStrategy) ShouldResolveIP() bool {
+	return cs.shouldResolveIP
+}
+
+func (cs *classicalStrategy) ShouldFindProcess() bool {
+	return cs.shouldFindProcess
+}
+
+func (cs *classicalStrategy) Count() int {
+	return len(cs.rules)
+}
+
+type RuleSetProvider struct {
+	*fetcher
+	behavior RuleBehavior
+	// strategy is replaced by the fetcher while the tunnel is matching
+	strategy ruleStrategy
+	mux      sync.RWMutex
+}
+
+func (rp *RuleSetProvider) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"name":        rp.Name(),
+		"type":        rp.Type().String(),
+		"vehicleType": rp.VehicleType().String(),
+		"behavior":    rp.Behavior().String(),
+		"ruleCount":   rp.getStrategy().Count(),
+		"updatedAt":   rp.UpdatedAt(),
+	})
+}
+
+func (rp *RuleSetProvider) Reload() error {
+	return nil
+}
+
+func (rp *RuleSetProvider) Type() ProviderType {
+	return Rule
+}
+
+func (rp *RuleSetProvider) Behavior() RuleBehavior {
+	return rp.behavior
+}
+
+func (rp *RuleSetProvider) getStrategy() ruleStrategy {
+	rp.mux.RLock()
+	defer rp.mux.RUnlock()
+	return rp.strategy
+}
+
+func (rp *RuleSetProvider) Match(metadata *C.Metadata) bool {
+	return rp.getStrategy().Match(metadata)
+}
+
+func (rp *RuleSetProvider) ShouldResolveIP() bool {
+	return rp.getStrategy().ShouldResolveIP()
+}
+
+func (rp *RuleSetProvider) ShouldFindProcess() bool {
+	return rp.getStrategy().ShouldFindProcess()
+}
+
+func (rp *RuleSetProvider) parse(buf []byte) (interface{}, error) {
+	schema := &RuleSchema{}
+
+	if err := yaml.Unmarshal(buf, schema); err != nil {
+		return nil, err
+	}
+
+	if schema.Payload == nil {
+		return nil, errors.New("File must have a `payload` field")
+	}
+
+	switch rp.behavior {
+	case Domain:
+		return parseDomainPayload(schema.Payload)
+	case IPCIDR:
+		return parseIPCIDRPayload(schema.Payload)
+	default:
+		return parseClassicalPayload(schema.Payload)
+	}
+}
+
+func parseDomainPayload(payload []string) (ruleStrategy, error) {
+	domains := trie.New()
+	for idx, domain := range payload {
+		if err := domains.Insert(strings.ToLower(domain), true); err != nil {
+			return nil, fmt.Errorf("Payload[%d] [%s] error: %w", idx, domain, err)
+		}
+	}
+
+	return &domainStrategy{domains: domains, count: len(payload)}, nil
+}
+
+func parseIPCIDRPayload(payload []string) (ruleStrategy, error) {
+	ipnets := cidr.New()
+	for idx, line := range payload {
+		_, ipnet, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, fmt.Errorf("Payload[%d] [%s] error: %w", idx, line, err)
+		}
+		ipnets.Insert(ipnet, true)
+	}
+
+	return &ipcidrStrategy{ipnets: ipnets, count: len(payload)}, nil
+}
+
+func parseClassicalPayload(payload []string) (ruleStrategy, error) {
+	strategy := &classicalStrategy{rules: make([]C.Rule, 0, len(payload))}
+	for idx, line := range payload {
+		rule, err := R.SplitRuleLine(line, false)
+		if err != nil {
+			return nil, fmt.Errorf("Payload[%d] [%s] error: %w", idx, line, err)
+		}
+
+		if len(rule) < 2 {
+			return nil, fmt.Errorf("Payload[%d] [%s] error: format invalid", idx, line)
+		}
+
+		parsed, err := R.ParseRule(rule[0], rule[1], "", rule[2:])
+		if err != nil {
+			return nil, fmt.Errorf("Payload[%d] [%s] error: %w", idx, line, err)
+		}
+
+		if !parsed.NoResolveIP() {
+			strategy.shouldResolveIP = true
+		}
+		if parsed.ShouldFindProcess() {
+			strategy.shouldFindProcess = true
+		}
+		strategy.rules = append(strategy.rules, parsed)
+	}
+
+	return strategy, nil
+}
+
+func NewRuleSetProvider(name string, behavior RuleBehavior, interval time.Duration, vehicle Vehicle) *RuleSetProvider {
+	rp := &RuleSetProvider{
+		behavior: behavior,
+		strategy: &classicalStrategy{},
+	}
+
+	onUpdate := func(elm interface{}) {
+		rp.mux.Lock()
+		rp.strategy = elm.(ruleStrategy)
+		rp.mux.Unlock()
+	}
+
+	rp.fetcher = newFetcher(name, interval, vehicle, rp.parse, onUpdate)
+	return rp
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func domainMetadata(host string) *C.Metadata {
+	return &C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: host, DstPort: "443"}
+}
+
+func ipMetadata(ip string) *C.Metadata {
+	return &C.Metadata{NetWork: C.TCP, AddrType: C.AtypIPv4, DstIP: net.ParseIP(ip), DstPort: "443"}
+}
+
+func TestRuleSetProvider_Domain(t *testing.T) {
+	rp := &RuleSetProvider{behavior: Domain}
+	elm, err := rp.parse([]byte("payload:\n  - 'example.com'\n  - '+.Google.com'\n  - '*.example.org'\n"))
+	assert.Nil(t, err)
+
+	strategy := elm.(ruleStrategy)
+	assert.Equal(t, 3, strategy.Count())
+	assert.False(t, strategy.ShouldResolveIP())
+	assert.True(t, strategy.Match(domainMetadata("example.com")))
+	assert.False(t, strategy.Match(domainMetadata("www.example.com")))
+	assert.True(t, strategy.Match(domainMetadata("google.com")))
+	assert.True(t, strategy.Match(domainMetadata("mail.google.com")))
+	assert.True(t, strategy.Match(domainMetadata("www.example.org")))
+	assert.False(t, strategy.Match(domainMetadata("example.org")))
+	assert.False(t, strategy.Match(ipMetadata("1.1.1.1")))
+}
+
+func TestRuleSetProvider_IPCIDR(t *testing.T) {
+	rp := &RuleSetProvider{behavior: IPCIDR}
+	elm, err := rp.parse([]byte("payload:\n  - '192.168.0.0/16'\n  - '10.0.0.1/32'\n"))
+	assert.Nil(t, err)
+
+	strategy := elm.(ruleStrategy)
+	assert.Equal(t, 2, strategy.Count())
+	assert.True(t, strategy.ShouldResolveIP())
+	assert.True(t, strategy.Match(ipMetadata("192.168.1.1")))
+	assert.True(t, strategy.Match(ipMetadata("10.0.0.1")))
+	assert.False(t, strategy.Match(ipMetadata("10.0.0.2")))
+	assert.False(t, strategy.Match(domainMetadata("example.com")))
+
+	_, err = rp.parse([]byte("payload:\n  - '192.168.0.0'\n"))
+	assert.NotNil(t, err)
+}
+
+func TestRuleSetProvider_Classical(t *testing.T) {
+	rp := &RuleSetProvider{behavior: Classical}
+	elm, err := rp.parse([]byte("payload:\n  - DOMAIN-SUFFIX,example.com\n  - IP-CIDR,10.0.0.0/8,no-resolve\n  - DST-PORT,8443\n"))
+	assert.Nil(t, err)
+
+	strategy := elm.(ruleStrategy)
+	assert.Equal(t, 3, strategy.Count())
+	assert.False(t, strategy.ShouldResolveIP())
+	assert.True(t, strategy.Match(domainMetadata("www.example.com")))
+	assert.True(t, strategy.Match(ipMetadata("10.1.1.1")))
+	assert.False(t, strategy.Match(ipMetadata("1.1.1.1")))
+
+	metadata := domainMetadata("example.org")
+	metadata.DstPort = "8443"
+	assert.True(t, strategy.Match(metadata))
+
+	elm, err = rp.parse([]byte("payload:\n  - IP-CIDR,10.0.0.0/8\n"))
+	assert.Nil(t, err)
+	assert.True(t, elm.(ruleStrategy).ShouldResolveIP())
+
+	// the pattern of DOMAIN-REGEX keeps its commas
+	elm, err = rp.parse([]byte("payload:\n  - 'DOMAIN-REGEX,^a{1,3}\\.example\\.com$'\n  - 'AND,((DOMAIN,example.net),(DST-PORT,443))'\n"))
+	assert.Nil(t, err)
+	assert.True(t, elm.(ruleStrategy).Match(domainMetadata("aa.example.com")))
+	assert.False(t, elm.(ruleStrategy).Match(domainMetadata("aaaa.example.com")))
+	assert.True(t, elm.(ruleStrategy).Match(domainMetadata("example.net")))
+
+	for _, payload := range []string{"payload:\n  - DOMAIN\n", "payload:\n  - UNKNOWN,a\n", "rules: []\n"} {
+		_, err = rp.parse([]byte(payload))
+		assert.NotNil(t, err, payload)
+	}
+}
+
+func TestRuleSetProvider_Update(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clash-rule-provider")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source.yaml")
+	assert.Nil(t, ioutil.WriteFile(source, []byte("payload:\n  - 'example.com'\n"), 0644))
+
+	rp := NewRuleSetProvider("rules", Domain, 0, NewHTTPVehicle("", filepath.Join(dir, "rules.yaml")))
+	rp.fetcher.vehicle = &sourceVehicle{Vehicle: rp.fetcher.vehicle, source: source}
+	assert.Nil(t, rp.Initial())
+	assert.True(t, rp.Match(domainMetadata("example.com")))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			rp.Match(domainMetadata("example.org"))
+			rp.ShouldResolveIP()
+		}
+	}()
+
+	assert.Nil(t, ioutil.WriteFile(source, []byte("payload:\n  - 'example.org'\n"), 0644))
+	assert.Nil(t, rp.Update())
+	<-done
+
+	assert.NotNil(t, rp.UpdatedAt())
+	assert.False(t, rp.Match(domainMetadata("example.com")))
+	assert.True(t, rp.Match(domainMetadata("example.org")))
+}
+
+// sourceVehicle reads the content from another file than the one it keeps
+type sourceVehicle struct {
+	Vehicle
+	source string
+}
+
+func (sv *sourceVehicle) Read() ([]byte, error) {
+	return ioutil.ReadFile(sv.source)
+}
@@ -0,0 +1,444 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Dreamacro/clash/log"
+)
+
+// Subscription format
+const (
+	formatYAML subscriptionFormat = iota
+	formatURIList
+)
+
+type subscriptionFormat int
+
+var (
+	errInvalidURI        = errors.New("invalid uri")
+	errUnsupportedScheme = errors.New("unsupported uri scheme")
+
+	base64Encodings = []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	}
+)
+
+// detectFormat tells a yaml file from a list of share uris, which is either
+// plain or base64 encoded. The uri list is returned decoded
+func detectFormat(buf []byte) (subscriptionFormat, []byte) {
+	if isURIList(buf) {
+		return formatURIList, buf
+	}
+
+	if decoded, err := decodeBase64(string(buf)); err == nil && isURIList(decoded) {
+		return formatURIList, decoded
+	}
+
+	return formatYAML, buf
+}
+
+// isURIList reports whether the first non-empty line of buf is an uri
+func isURIList(buf []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		return strings.Contains(line, "://") && !strings.ContainsAny(line[:strings.Index(line, "://")], " :")
+	}
+	return false
+}
+
+// decodeBase64 decodes s with or without padding, in the standard or the
+// url alphabet
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+
+	var err error
+	for _, encoding := range base64Encodings {
+		var buf []byte
+		if buf, err = encoding.DecodeString(s); err == nil {
+			return buf, nil
+		}
+	}
+	return nil, err
+}
+
+// parseURIList converts every uri of buf to a proxy mapping, the invalid lines
+// and the ones of unsupported schemes are skipped
+func parseURIList(buf []byte) ([]map[string]interface{}, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	mappings := []map[string]interface{}{}
+	for idx, line := range lines {
+		mapping, err := parseURI(line)
+		if err != nil {
+			log.Warnln("Proxy %d: %s", idx, err.Error())
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, nil
+}
+
+func parseURI(uri string) (map[string]interface{}, error) {
+	idx := strings.Index(uri, "://")
+	if idx == -1 {
+		return nil, errInvalidURI
+	}
+
+	switch strings.ToLower(uri[:idx]) {
+	case "ss":
+		return parseSSURI(uri)
+	case "ssr":
+		return parseSSRURI(uri)
+	case "vmess":
+		return parseVmessURI(uri)
+	case "trojan":
+		return parseTrojanURI(uri)
+	default:
+		return nil, errUnsupportedScheme
+	}
+}
+
+// parseSSURI parses both SIP002 `ss://base64(method:password)@server:port/?plugin=...#name`
+// and the legacy `ss://base64(method:password@server:port)#name`
+func parseSSURI(uri string) (map[string]interface{}, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+
+	// legacy
+	if u.User == nil {
+		decoded, err := decodeBase64(u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+		}
+		fragment := u.Fragment
+		if u, err = url.Parse("ss://" + string(decoded)); err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+		}
+		u.Fragment = fragment
+	}
+
+	server, port, err := splitHostPort(u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user info is base64 encoded unless the url has a password
+	cipher, password := u.User.Username(), ""
+	if pass, ok := u.User.Password(); ok {
+		password = pass
+	} else {
+		decoded, err := decodeBase64(cipher)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: missing password", errInvalidURI)
+		}
+		cipher, password = parts[0], parts[1]
+	}
+
+	mapping := map[string]interface{}{
+		"name":     proxyName(u.Fragment, u.Host),
+		"type":     "ss",
+		"server":   server,
+		"port":     port,
+		"cipher":   cipher,
+		"password": password,
+		"udp":      true,
+	}
+
+	if plugin := u.Query().Get("plugin"); plugin != "" {
+		name, opts := parseSSPlugin(plugin)
+		mapping["plugin"] = name
+		mapping["plugin-opts"] = opts
+	}
+
+	return mapping, nil
+}
+
+// parseSSPlugin converts a SIP003 plugin `name;key=value;flag` to the plugin
+// and plugin-opts of ss
+func parseSSPlugin(plugin string) (string, map[string]interface{}) {
+	parts := strings.Split(plugin, ";")
+	args := map[string]string{}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			args[kv[0]] = "true"
+		} else {
+			args[kv[0]] = kv[1]
+		}
+	}
+
+	opts := map[string]interface{}{}
+	switch parts[0] {
+	case "obfs-local", "simple-obfs", "obfs":
+		opts["mode"] = args["obfs"]
+		if host, ok := args["obfs-host"]; ok {
+			opts["host"] = host
+		}
+		return "obfs", opts
+	case "v2ray-plugin":
+		opts["mode"] = "websocket"
+		if mode, ok := args["mode"]; ok {
+			opts["mode"] = mode
+		}
+		for _, key := range []string{"host", "path"} {
+			if value, ok := args[key]; ok {
+				opts[key] = value
+			}
+		}
+		if _, ok := args["tls"]; ok {
+			opts["tls"] = true
+		}
+		if mux, ok := args["mux"]; ok {
+			opts["mux"] = mux != "0" && mux != "false"
+		}
+		return "v2ray-plugin", opts
+	default:
+		for key, value := range args {
+			opts[key] = value
+		}
+		return parts[0], opts
+	}
+}
+
+// parseSSRURI parses `ssr://base64(server:port:protocol:cipher:obfs:base64(password)/?obfsparam=...)`,
+// every parameter is base64 encoded
+func parseSSRURI(uri string) (map[string]interface{}, error) {
+	decoded, err := decodeBase64(uri[len("ssr://"):])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+
+	body, rawQuery := string(decoded), ""
+	if idx := strings.Index(body, "/?"); idx != -1 {
+		body, rawQuery = body[:idx], body[idx+2:]
+	} else if idx := strings.Index(body, "?"); idx != -1 {
+		body, rawQuery = body[:idx], body[idx+1:]
+	}
+
+	// the server may be an ipv6 address, so the fields are taken from the right
+	parts := strings.Split(body, ":")
+	if len(parts) < 6 {
+		return nil, fmt.Errorf("%w: missing fields", errInvalidURI)
+	}
+	n := len(parts)
+	server := strings.Trim(strings.Join(parts[:n-5], ":"), "[]")
+	port, err := strconv.Atoi(parts[n-5])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid port %s", errInvalidURI, parts[n-5])
+	}
+	password, err := decodeBase64(parts[n-1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+	param := func(key string) string {
+		value, _ := decodeBase64(query.Get(key))
+		return string(value)
+	}
+
+	return map[string]interface{}{
+		"name":           proxyName(param("remarks"), net.JoinHostPort(server, parts[n-5])),
+		"type":           "ssr",
+		"server":         server,
+		"port":           port,
+		"protocol":       parts[n-4],
+		"cipher":         parts[n-3],
+		"obfs":           parts[n-2],
+		"password":       string(password),
+		"obfs-param":     param("obfsparam"),
+		"protocol-param": param("protoparam"),
+	}, nil
+}
+
+// vmessLink is the v2rayN share link, port and aid are either strings or numbers
+type vmessLink struct {
+	PS   string      `json:"ps"`
+	Add  string      `json:"add"`
+	Port interface{} `json:"port"`
+	ID   string      `json:"id"`
+	Aid  interface{} `json:"aid"`
+	Scy  string      `json:"scy"`
+	Net  string      `json:"net"`
+	Host string      `json:"host"`
+	Path string      `json:"path"`
+	TLS  string      `json:"tls"`
+}
+
+// parseVmessURI parses the v2rayN `vmess://base64(json)`
+func parseVmessURI(uri string) (map[string]interface{}, error) {
+	decoded, err := decodeBase64(uri[len("vmess://"):])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+
+	link := &vmessLink{}
+	if err := json.Unmarshal(decoded, link); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+
+	port, err := jsonInt(link.Port)
+	if err != nil || port == 0 {
+		return nil, fmt.Errorf("%w: invalid port %v", errInvalidURI, link.Port)
+	}
+	alterID, err := jsonInt(link.Aid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid aid %v", errInvalidURI, link.Aid)
+	}
+
+	cipher := link.Scy
+	if cipher == "" {
+		cipher = "auto"
+	}
+
+	mapping := map[string]interface{}{
+		"name":    proxyName(link.PS, net.JoinHostPort(link.Add, strconv.Itoa(port))),
+		"type":    "vmess",
+		"server":  link.Add,
+		"port":    port,
+		"uuid":    link.ID,
+		"alterId": alterID,
+		"cipher":  cipher,
+		"udp":     true,
+		"tls":     link.TLS == "tls",
+	}
+
+	switch link.Net {
+	case "ws":
+		mapping["network"] = "ws"
+		if link.Path != "" {
+			mapping["ws-path"] = link.Path
+		}
+		if link.Host != "" {
+			mapping["ws-headers"] = map[string]interface{}{"Host": link.Host}
+		}
+	case "h2", "http":
+		mapping["network"] = "h2"
+		opts := map[string]interface{}{}
+		if link.Host != "" {
+			opts["host"] = strings.Split(link.Host, ",")
+		}
+		if link.Path != "" {
+			opts["path"] = link.Path
+		}
+		mapping["h2-opts"] = opts
+	case "grpc":
+		mapping["network"] = "grpc"
+		mapping["grpc-opts"] = map[string]interface{}{"grpc-service-name": link.Path}
+	}
+
+	return mapping, nil
+}
+
+// parseTrojanURI parses `trojan://password@server:port?sni=...&allowInsecure=1#name`
+func parseTrojanURI(uri string) (map[string]interface{}, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("%w: missing password", errInvalidURI)
+	}
+
+	server, port, err := splitHostPort(u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := map[string]interface{}{
+		"name":     proxyName(u.Fragment, u.Host),
+		"type":     "trojan",
+		"server":   server,
+		"port":     port,
+		"password": u.User.Username(),
+		"udp":      true,
+	}
+
+	query := u.Query()
+	if sni := query.Get("sni"); sni != "" {
+		mapping["sni"] = sni
+	} else if peer := query.Get("peer"); peer != "" {
+		mapping["sni"] = peer
+	}
+	if alpn := query.Get("alpn"); alpn != "" {
+		mapping["alpn"] = strings.Split(alpn, ",")
+	}
+	if insecure := query.Get("allowInsecure"); insecure == "1" || insecure == "true" {
+		mapping["skip-cert-verify"] = true
+	}
+
+	return mapping, nil
+}
+
+// jsonInt reads an int which may be a json number, a string or missing
+func jsonInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(n), nil
+	case string:
+		if n == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(n)
+	default:
+		return 0, fmt.Errorf("unexpected %T", v)
+	}
+}
+
+func splitHostPort(hostport string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %s", errInvalidURI, err.Error())
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: invalid port %s", errInvalidURI, portStr)
+	}
+	return host, port, nil
+}
+
+// proxyName falls back to the address when the uri has no name
+func proxyName(name, addr string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return addr
+}
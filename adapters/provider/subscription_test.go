@@ -0,0 +1,233 @@
+package provider
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testUUID = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+func TestParseSSURI(t *testing.T) {
+	cases := []struct {
+		uri      string
+		expected map[string]interface{}
+	}{
+		{
+			uri:      "ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4:8388#HK%2001",
+			expected: map[string]interface{}{"name": "HK 01", "type": "ss", "server": "1.2.3.4", "port": 8388, "cipher": "aes-256-gcm", "password": "pass", "udp": true},
+		},
+		{
+			// legacy
+			uri:      "ss://YWVzLTEyOC1nY206cEBzc0AxLjIuMy40OjgzODg=#legacy",
+			expected: map[string]interface{}{"name": "legacy", "type": "ss", "server": "1.2.3.4", "port": 8388, "cipher": "aes-128-gcm", "password": "p@ss", "udp": true},
+		},
+		{
+			// plain user info of the 2022 ciphers, the name falls back to the address
+			uri:      "ss://2022-blake3-aes-128-gcm:a2V5a2V5a2V5a2V5a2V5aw%3D%3D@[::1]:443",
+			expected: map[string]interface{}{"name": "[::1]:443", "type": "ss", "server": "::1", "port": 443, "cipher": "2022-blake3-aes-128-gcm", "password": "a2V5a2V5a2V5a2V5a2V5aw==", "udp": true},
+		},
+		{
+			uri: "ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4:8388/?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dbing.com#obfs",
+			expected: map[string]interface{}{
+				"name": "obfs", "type": "ss", "server": "1.2.3.4", "port": 8388, "cipher": "aes-256-gcm", "password": "pass", "udp": true,
+				"plugin": "obfs", "plugin-opts": map[string]interface{}{"mode": "http", "host": "bing.com"},
+			},
+		},
+		{
+			uri: "ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4:443/?plugin=v2ray-plugin%3Btls%3Bhost%3Dexample.com%3Bpath%3D%2Fws#v2ray",
+			expected: map[string]interface{}{
+				"name": "v2ray", "type": "ss", "server": "1.2.3.4", "port": 443, "cipher": "aes-256-gcm", "password": "pass", "udp": true,
+				"plugin": "v2ray-plugin", "plugin-opts": map[string]interface{}{"mode": "websocket", "host": "example.com", "path": "/ws", "tls": true},
+			},
+		},
+		{uri: "ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4#no-port"},
+		{uri: "ss://bm90LWEtY2lwaGVy@1.2.3.4:8388"},
+	}
+
+	for _, c := range cases {
+		mapping, err := parseURI(c.uri)
+		if c.expected == nil {
+			assert.NotNil(t, err, c.uri)
+			continue
+		}
+		assert.Nil(t, err, c.uri)
+		assert.Equal(t, c.expected, mapping, c.uri)
+	}
+}
+
+func TestParseSSRURI(t *testing.T) {
+	cases := []struct {
+		uri      string
+		expected map[string]interface{}
+	}{
+		{
+			uri: "ssr://MS4yLjMuNDo0NDM6YXV0aF9hZXMxMjhfbWQ1OmNoYWNoYTIwLWlldGY6dGxzMS4yX3RpY2tldF9hdXRoOmNHRnpjM2R2Y21RLz9vYmZzcGFyYW09WW1sdVp5NWpiMjAmcHJvdG9wYXJhbT1NVEF5TkRwd2R3JnJlbWFya3M9NmFhWjVyaXZJREF4",
+			expected: map[string]interface{}{
+				"name": "香港 01", "type": "ssr", "server": "1.2.3.4", "port": 443, "password": "password",
+				"cipher": "chacha20-ietf", "protocol": "auth_aes128_md5", "protocol-param": "1024:pw",
+				"obfs": "tls1.2_ticket_auth", "obfs-param": "bing.com",
+			},
+		},
+		{
+			// ipv6 server without parameters
+			uri: "ssr://MjAwMTpkYjg6OjE6ODM4ODpvcmlnaW46YWVzLTI1Ni1jZmI6cGxhaW46Y0hj",
+			expected: map[string]interface{}{
+				"name": "[2001:db8::1]:8388", "type": "ssr", "server": "2001:db8::1", "port": 8388, "password": "pw",
+				"cipher": "aes-256-cfb", "protocol": "origin", "protocol-param": "", "obfs": "plain", "obfs-param": "",
+			},
+		},
+		{uri: "ssr://" + base64.RawURLEncoding.EncodeToString([]byte("1.2.3.4:443:origin:plain"))},
+		{uri: "ssr://not base64"},
+	}
+
+	for _, c := range cases {
+		mapping, err := parseURI(c.uri)
+		if c.expected == nil {
+			assert.NotNil(t, err, c.uri)
+			continue
+		}
+		assert.Nil(t, err, c.uri)
+		assert.Equal(t, c.expected, mapping, c.uri)
+	}
+}
+
+func TestParseVmessURI(t *testing.T) {
+	cases := []struct {
+		uri      string
+		expected map[string]interface{}
+	}{
+		{
+			uri: "vmess://eyJ2IjoiMiIsInBzIjoiSlAgMDEiLCJhZGQiOiJleGFtcGxlLmNvbSIsInBvcnQiOiI0NDMiLCJpZCI6ImI4MzEzODFkLTYzMjQtNGQ1My1hZDRmLThjZGE0OGIzMDgxMSIsImFpZCI6IjAiLCJuZXQiOiJ3cyIsInR5cGUiOiJub25lIiwiaG9zdCI6ImNkbi5leGFtcGxlLmNvbSIsInBhdGgiOiIvd3MiLCJ0bHMiOiJ0bHMifQ==",
+			expected: map[string]interface{}{
+				"name": "JP 01", "type": "vmess", "server": "example.com", "port": 443, "uuid": testUUID, "alterId": 0,
+				"cipher": "auto", "udp": true, "tls": true,
+				"network": "ws", "ws-path": "/ws", "ws-headers": map[string]interface{}{"Host": "cdn.example.com"},
+			},
+		},
+		{
+			// numbers instead of strings, no name
+			uri: "vmess://eyJ2IjoiMiIsInBzIjoiIiwiYWRkIjoiMS4yLjMuNCIsInBvcnQiOjEwMDg2LCJpZCI6ImI4MzEzODFkLTYzMjQtNGQ1My1hZDRmLThjZGE0OGIzMDgxMSIsImFpZCI6NjQsInNjeSI6ImFlcy0xMjgtZ2NtIiwibmV0IjoiZ3JwYyIsInBhdGgiOiJzdmMiLCJ0bHMiOiIifQ==",
+			expected: map[string]interface{}{
+				"name": "1.2.3.4:10086", "type": "vmess", "server": "1.2.3.4", "port": 10086, "uuid": testUUID, "alterId": 64,
+				"cipher": "aes-128-gcm", "udp": true, "tls": false,
+				"network": "grpc", "grpc-opts": map[string]interface{}{"grpc-service-name": "svc"},
+			},
+		},
+		{
+			uri: "vmess://eyJwcyI6ImgyIiwiYWRkIjoiMS4yLjMuNCIsInBvcnQiOiI0NDMiLCJpZCI6ImI4MzEzODFkLTYzMjQtNGQ1My1hZDRmLThjZGE0OGIzMDgxMSIsImFpZCI6IiIsIm5ldCI6ImgyIiwiaG9zdCI6ImEuY29tLGIuY29tIiwicGF0aCI6Ii9oMiIsInRscyI6InRscyJ9",
+			expected: map[string]interface{}{
+				"name": "h2", "type": "vmess", "server": "1.2.3.4", "port": 443, "uuid": testUUID, "alterId": 0,
+				"cipher": "auto", "udp": true, "tls": true,
+				"network": "h2", "h2-opts": map[string]interface{}{"host": []string{"a.com", "b.com"}, "path": "/h2"},
+			},
+		},
+		{uri: "vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"add":"1.2.3.4","port":"http"}`))},
+		{uri: "vmess://" + base64.StdEncoding.EncodeToString([]byte(`not json`))},
+	}
+
+	for _, c := range cases {
+		mapping, err := parseURI(c.uri)
+		if c.expected == nil {
+			assert.NotNil(t, err, c.uri)
+			continue
+		}
+		assert.Nil(t, err, c.uri)
+		assert.Equal(t, c.expected, mapping, c.uri)
+	}
+}
+
+func TestParseTrojanURI(t *testing.T) {
+	cases := []struct {
+		uri      string
+		expected map[string]interface{}
+	}{
+		{
+			uri: "trojan://p%40ss@example.com:443?sni=sni.example.com&alpn=h2,http/1.1&allowInsecure=1#US%2001",
+			expected: map[string]interface{}{
+				"name": "US 01", "type": "trojan", "server": "example.com", "port": 443, "password": "p@ss", "udp": true,
+				"sni": "sni.example.com", "alpn": []string{"h2", "http/1.1"}, "skip-cert-verify": true,
+			},
+		},
+		{
+			uri: "trojan://pass@1.2.3.4:8443?peer=peer.example.com",
+			expected: map[string]interface{}{
+				"name": "1.2.3.4:8443", "type": "trojan", "server": "1.2.3.4", "port": 8443, "password": "pass", "udp": true,
+				"sni": "peer.example.com",
+			},
+		},
+		{uri: "trojan://example.com:443"},
+		{uri: "trojan://pass@example.com"},
+	}
+
+	for _, c := range cases {
+		mapping, err := parseURI(c.uri)
+		if c.expected == nil {
+			assert.NotNil(t, err, c.uri)
+			continue
+		}
+		assert.Nil(t, err, c.uri)
+		assert.Equal(t, c.expected, mapping, c.uri)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	uris := "ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4:8388#ss\ntrojan://pass@1.2.3.4:443#trojan\n"
+
+	cases := []struct {
+		buf      string
+		format   subscriptionFormat
+		expected string
+	}{
+		{uris, formatURIList, uris},
+		{base64.StdEncoding.EncodeToString([]byte(uris)), formatURIList, uris},
+		// base64 wrapped at 76 columns
+		{strings.Join(chunk(base64.StdEncoding.EncodeToString([]byte(uris)), 76), "\r\n"), formatURIList, uris},
+		{"# from https://example.com\nproxies:\n", formatYAML, "# from https://example.com\nproxies:\n"},
+		{"proxies:\n  - name: ss\n", formatYAML, "proxies:\n  - name: ss\n"},
+	}
+
+	for _, c := range cases {
+		format, decoded := detectFormat([]byte(c.buf))
+		assert.Equal(t, c.format, format, c.buf)
+		assert.Equal(t, c.expected, string(decoded), c.buf)
+	}
+}
+
+func chunk(s string, size int) []string {
+	chunks := []string{}
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
+
+func TestProxiesParse_Subscription(t *testing.T) {
+	uris := strings.Join([]string{
+		"ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4:8388#ss",
+		"",
+		"vless://" + testUUID + "@1.2.3.4:443#unsupported",
+		"ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4#no-port",
+		"ss://" + base64.RawURLEncoding.EncodeToString([]byte("unknown:pass")) + "@1.2.3.4:8388#bad-cipher",
+		"trojan://pass@1.2.3.4:443#trojan",
+	}, "\n")
+
+	elm, err := proxiesParse([]byte(base64.StdEncoding.EncodeToString([]byte(uris))))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	names := []string{}
+	for _, proxy := range elm.([]C.Proxy) {
+		names = append(names, proxy.Name())
+	}
+	assert.Equal(t, []string{"ss", "trojan"}, names)
+
+	_, err = proxiesParse([]byte("ss://YWVzLTI1Ni1nY206cGFzcw@1.2.3.4#no-port"))
+	assert.NotNil(t, err)
+}
@@ -97,6 +97,25 @@ func (c *LruCache) Get(key interface{}) (interface{}, bool) {
 	return value, true
 }
 
+// Peek returns the value of key like Get, but neither moves it in the lru list
+// nor updates its age
+func (c *LruCache) Peek(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	le, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := le.Value.(*entry)
+	if c.maxAge > 0 && entry.expires <= time.Now().Unix() {
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
 // Exist returns if key exist in cache but not put item to the head of linked list
 func (c *LruCache) Exist(key interface{}) bool {
 	c.mu.Lock()
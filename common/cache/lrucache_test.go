@@ -102,6 +102,29 @@ func TestLRUpdateOnGet(t *testing.T) {
 	assert.True(t, c.lru.Back().Value.(*entry).expires > expires)
 }
 
+func TestPeek(t *testing.T) {
+	c := NewLRUCache(WithAge(86400), WithUpdateAgeOnGet())
+
+	expires := time.Now().Unix() + 86400/2
+	c.Set("foo", "bar")
+	c.Set("baz", "qux")
+	c.lru.Front().Value.(*entry).expires = expires
+
+	// neither the order nor the age changes
+	value, ok := c.Peek("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+	assert.Equal(t, "foo", c.lru.Front().Value.(*entry).key)
+	assert.Equal(t, expires, c.lru.Front().Value.(*entry).expires)
+
+	c.lru.Front().Value.(*entry).expires = time.Now().Unix()
+	_, ok = c.Peek("foo")
+	assert.False(t, ok)
+
+	_, ok = c.Peek("missing")
+	assert.False(t, ok)
+}
+
 func TestMaxSize(t *testing.T) {
 	c := NewLRUCache(WithSize(2))
 	// Add one expired entry
@@ -0,0 +1,115 @@
+package cachefile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	C "github.com/Dreamacro/clash/constant"
+	"github.com/Dreamacro/clash/log"
+)
+
+const fileMode = 0666
+
+var (
+	defaultCache *CacheFile
+	once         sync.Once
+)
+
+// CacheFile is a small json store of the state kept across restarts
+type CacheFile struct {
+	path  string
+	mux   sync.Mutex
+	model *cacheModel
+}
+
+type cacheModel struct {
+	Selected map[string]string `json:"selected"`
+}
+
+// SelectedMap returns a copy of the group -> selected proxy mappings
+func (c *CacheFile) SelectedMap() map[string]string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	mapping := map[string]string{}
+	for group, selected := range c.model.Selected {
+		mapping[group] = selected
+	}
+	return mapping
+}
+
+// SetSelected records the proxy selected in group
+func (c *CacheFile) SetSelected(group, selected string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.model.Selected[group] == selected {
+		return
+	}
+	c.model.Selected[group] = selected
+	c.flush()
+}
+
+// SetSelectedMap replaces all the mappings, the file is left untouched if
+// nothing changes
+func (c *CacheFile) SetSelectedMap(mapping map[string]string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	changed := len(mapping) != len(c.model.Selected)
+	for group, selected := range mapping {
+		if current, exist := c.model.Selected[group]; !exist || current != selected {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	c.model.Selected = map[string]string{}
+	for group, selected := range mapping {
+		c.model.Selected[group] = selected
+	}
+	c.flush()
+}
+
+func (c *CacheFile) flush() {
+	buf, err := json.Marshal(c.model)
+	if err != nil {
+		log.Warnln("[CacheFile] marshal error: %s", err.Error())
+		return
+	}
+
+	if err := ioutil.WriteFile(c.path, buf, fileMode); err != nil {
+		log.Warnln("[CacheFile] write %s error: %s", c.path, err.Error())
+	}
+}
+
+// New loads the cache file of path, a missing or broken file is an empty cache
+func New(path string) *CacheFile {
+	model := &cacheModel{}
+	if buf, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(buf, model); err != nil {
+			log.Warnln("[CacheFile] %s is broken: %s", path, err.Error())
+		}
+	} else if !os.IsNotExist(err) {
+		log.Warnln("[CacheFile] read %s error: %s", path, err.Error())
+	}
+
+	if model.Selected == nil {
+		model.Selected = map[string]string{}
+	}
+
+	return &CacheFile{path: path, model: model}
+}
+
+// Cache returns the cache file in the home dir
+func Cache() *CacheFile {
+	once.Do(func() {
+		defaultCache = New(C.Path.Cache())
+	})
+
+	return defaultCache
+}
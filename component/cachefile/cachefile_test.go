@@ -0,0 +1,42 @@
+package cachefile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clash-cachefile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.json")
+
+	cache := New(path)
+	assert.Empty(t, cache.SelectedMap())
+
+	cache.SetSelected("proxy", "ss1")
+	cache.SetSelected("GLOBAL", "proxy")
+	assert.Equal(t, map[string]string{"proxy": "ss1", "GLOBAL": "proxy"}, New(path).SelectedMap())
+
+	// the stale mappings are cleared
+	cache.SetSelectedMap(map[string]string{"GLOBAL": "proxy"})
+	assert.Equal(t, map[string]string{"GLOBAL": "proxy"}, New(path).SelectedMap())
+}
+
+func TestCacheFile_Broken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clash-cachefile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.json")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("{"), fileMode))
+	cache := New(path)
+	assert.Empty(t, cache.SelectedMap())
+
+	cache.SetSelected("proxy", "ss1")
+	assert.Equal(t, map[string]string{"proxy": "ss1"}, New(path).SelectedMap())
+}
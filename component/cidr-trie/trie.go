@@ -0,0 +1,119 @@
+package cidr
+
+import (
+	"net"
+)
+
+type node struct {
+	data     interface{}
+	children [2]*node
+}
+
+// Trie is a binary prefix tree of IP networks, IPv4 and IPv6 are stored separately
+type Trie struct {
+	v4 *node
+	v6 *node
+}
+
+// normalize returns the network number and mask of ipnet in the same form
+// net.IPNet.Contains compares them
+func normalize(ipnet *net.IPNet) (net.IP, net.IPMask) {
+	ip, mask := ipnet.IP, ipnet.Mask
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+	}
+	return ip, mask
+}
+
+func (t *Trie) root(ip net.IP) (net.IP, **node) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, &t.v4
+	}
+	return ip.To16(), &t.v6
+}
+
+// Insert adds ipnet to the trie, the data of an existing network is replaced
+func (t *Trie) Insert(ipnet *net.IPNet, data interface{}) {
+	ip, mask := normalize(ipnet)
+	ones, bits := mask.Size()
+	if bits == 0 || bits != len(ip)*8 {
+		return
+	}
+
+	ip, root := t.root(ip)
+	if *root == nil {
+		*root = &node{}
+	}
+
+	n := *root
+	for i := 0; i < ones; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+	n.data = data
+}
+
+// Get returns the data of ipnet, nil if ipnet is not in the trie
+func (t *Trie) Get(ipnet *net.IPNet) interface{} {
+	ip, mask := normalize(ipnet)
+	ones, bits := mask.Size()
+	if bits == 0 || bits != len(ip)*8 {
+		return nil
+	}
+
+	ip, root := t.root(ip)
+	n := *root
+	for i := 0; i < ones && n != nil; i++ {
+		n = n.children[ip[i/8]>>(7-uint(i%8))&1]
+	}
+
+	if n == nil {
+		return nil
+	}
+	return n.data
+}
+
+// Walk calls fn with the data of every network that contains ip,
+// from the shortest prefix to the longest
+func (t *Trie) Walk(ip net.IP, fn func(data interface{})) {
+	ip, root := t.root(ip)
+	n := *root
+	if ip == nil || n == nil {
+		return
+	}
+
+	for i := 0; ; i++ {
+		if n.data != nil {
+			fn(n.data)
+		}
+
+		if i == len(ip)*8 {
+			return
+		}
+
+		n = n.children[ip[i/8]>>(7-uint(i%8))&1]
+		if n == nil {
+			return
+		}
+	}
+}
+
+// Contains reports whether any network in the trie contains ip
+func (t *Trie) Contains(ip net.IP) bool {
+	found := false
+	t.Walk(ip, func(interface{}) {
+		found = true
+	})
+	return found
+}
+
+// New returns a new, empty Trie.
+func New() *Trie {
+	return &Trie{}
+}
@@ -0,0 +1,89 @@
+package cidr
+
+import (
+	"net"
+	"testing"
+)
+
+func insert(tree *Trie, cidr string, data interface{}) {
+	_, ipnet, _ := net.ParseCIDR(cidr)
+	tree.Insert(ipnet, data)
+}
+
+func TestTrie_Basic(t *testing.T) {
+	tree := New()
+	insert(tree, "10.0.0.0/8", 1)
+	insert(tree, "192.168.1.0/24", 2)
+	insert(tree, "2001:db8::/32", 3)
+
+	if !tree.Contains(net.ParseIP("10.1.2.3")) {
+		t.Error("should contain 10.1.2.3")
+	}
+
+	if !tree.Contains(net.ParseIP("192.168.1.255")) {
+		t.Error("should contain 192.168.1.255")
+	}
+
+	if tree.Contains(net.ParseIP("192.168.2.1")) {
+		t.Error("should not contain 192.168.2.1")
+	}
+
+	if !tree.Contains(net.ParseIP("2001:db8::1")) {
+		t.Error("should contain 2001:db8::1")
+	}
+
+	if tree.Contains(net.ParseIP("::ffff:11.0.0.1")) {
+		t.Error("should not contain 11.0.0.1")
+	}
+}
+
+func TestTrie_Walk(t *testing.T) {
+	tree := New()
+	insert(tree, "0.0.0.0/0", 0)
+	insert(tree, "10.0.0.0/8", 8)
+	insert(tree, "10.1.0.0/16", 16)
+	insert(tree, "10.1.2.3/32", 32)
+	insert(tree, "10.2.0.0/16", -1)
+
+	visited := []int{}
+	tree.Walk(net.ParseIP("10.1.2.3"), func(data interface{}) {
+		visited = append(visited, data.(int))
+	})
+
+	expected := []int{0, 8, 16, 32}
+	if len(visited) != len(expected) {
+		t.Fatalf("unexpected walk: %v", visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Fatalf("unexpected walk: %v", visited)
+		}
+	}
+}
+
+func TestTrie_Get(t *testing.T) {
+	tree := New()
+	insert(tree, "10.0.0.0/8", 8)
+
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/8")
+	if tree.Get(ipnet) != 8 {
+		t.Error("should get 8")
+	}
+
+	_, ipnet, _ = net.ParseCIDR("10.0.0.0/16")
+	if tree.Get(ipnet) != nil {
+		t.Error("should get nil")
+	}
+}
+
+func TestTrie_Boundary(t *testing.T) {
+	tree := New()
+
+	if tree.Contains(net.ParseIP("127.0.0.1")) {
+		t.Error("empty trie should not contain anything")
+	}
+
+	if tree.Contains(nil) {
+		t.Error("nil ip should not be contained")
+	}
+}
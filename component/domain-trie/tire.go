@@ -6,8 +6,9 @@ import (
 )
 
 const (
-	wildcard   = "*"
-	domainStep = "."
+	wildcard        = "*"
+	complexWildcard = "+"
+	domainStep      = "."
 )
 
 var (
@@ -16,7 +17,7 @@ var (
 )
 
 // Trie contains the main logic for adding and searching nodes for domain segments.
-// support wildcard domain (e.g *.google.com)
+// support wildcard domain (e.g *.google.com) and suffix domain (e.g +.google.com)
 type Trie struct {
 	root *Node
 }
@@ -30,8 +31,9 @@ func isValidDomain(domain string) bool {
 // 1. www.example.com
 // 2. *.example.com
 // 3. subdomain.*.example.com
+// 4. +.example.com (example.com and all of its subdomains, only for SearchSuffix)
 func (t *Trie) Insert(domain string, data interface{}) error {
-	if !isValidDomain(domain) {
+	if !isValidDomain(domain) || domain == complexWildcard {
 		return ErrInvalidDomain
 	}
 
@@ -86,6 +88,73 @@ func (t *Trie) Search(domain string) *Node {
 	return n
 }
 
+// SearchSuffix is Search for the domain sets of rule providers and geosite,
+// it also matches suffix domains, and falls back to the wildcard and suffix
+// domains when a static part leads to no data.
+// Priority as:
+// 1. static part
+// 2. wildcard domain
+// 3. suffix domain
+func (t *Trie) SearchSuffix(domain string) *Node {
+	if !isValidDomain(domain) {
+		return nil
+	}
+	parts := strings.Split(domain, domainStep)
+
+	n := t.searchSuffix(t.root, parts)
+	if n == nil || n.Data == nil {
+		return nil
+	}
+
+	return n
+}
+
+func (t *Trie) searchSuffix(node *Node, parts []string) *Node {
+	if len(parts) == 0 {
+		if node.Data != nil {
+			return node
+		}
+		return node.getChild(complexWildcard)
+	}
+
+	last := len(parts) - 1
+	if child := node.getChild(parts[last]); child != nil {
+		if n := t.searchSuffix(child, parts[:last]); n != nil && n.Data != nil {
+			return n
+		}
+	}
+
+	if child := node.getChild(wildcard); child != nil {
+		if n := t.searchSuffix(child, parts[:last]); n != nil && n.Data != nil {
+			return n
+		}
+	}
+
+	return node.getChild(complexWildcard)
+}
+
+// Walk traverses the static parts of domain from the top level down, and calls fn
+// with the data of every node on the way that holds data. exact is true when
+// the node stands for the whole domain. Wildcards are not expanded.
+func (t *Trie) Walk(domain string, fn func(data interface{}, exact bool)) {
+	if !isValidDomain(domain) {
+		return
+	}
+	parts := strings.Split(domain, domainStep)
+
+	n := t.root
+	for i := len(parts) - 1; i >= 0; i-- {
+		n = n.getChild(parts[i])
+		if n == nil {
+			return
+		}
+
+		if n.Data != nil {
+			fn(n.Data, i == 0)
+		}
+	}
+}
+
 // New returns a new, empty Trie.
 func New() *Trie {
 	return &Trie{root: newNode(nil)}
@@ -85,3 +85,76 @@ func TestTrie_Boundary(t *testing.T) {
 		t.Error("should recv nil")
 	}
 }
+
+func TestTrie_Suffix(t *testing.T) {
+	tree := New()
+	tree.Insert("+.example.com", localIP)
+	tree.Insert("static.example.com", net.IP{127, 0, 0, 2})
+
+	if tree.SearchSuffix("example.com") == nil {
+		t.Error("should not recv nil")
+	}
+
+	if tree.SearchSuffix("foo.bar.example.com") == nil {
+		t.Error("should not recv nil")
+	}
+
+	if !tree.SearchSuffix("static.example.com").Data.(net.IP).Equal(net.IP{127, 0, 0, 2}) {
+		t.Error("should equal 127.0.0.2")
+	}
+
+	if tree.SearchSuffix("fooexample.com") != nil {
+		t.Error("should recv nil")
+	}
+
+	if err := tree.Insert("+", localIP); err == nil {
+		t.Error("should recv err")
+	}
+}
+
+func TestTrie_SearchUnchanged(t *testing.T) {
+	// hosts and fake-ip filter use Search, which neither knows suffix
+	// domains nor falls back from a static part
+	tree := New()
+	tree.Insert("+.example.com", localIP)
+	tree.Insert("a.b.example.org", localIP)
+	tree.Insert("c.*.example.org", localIP)
+
+	if tree.Search("example.com") != nil {
+		t.Error("should recv nil")
+	}
+
+	if tree.Search("foo.example.com") != nil {
+		t.Error("should recv nil")
+	}
+
+	if tree.Search("c.b.example.org") != nil {
+		t.Error("should recv nil")
+	}
+
+	if tree.SearchSuffix("c.b.example.org") == nil {
+		t.Error("should not recv nil")
+	}
+}
+
+func TestTrie_Walk(t *testing.T) {
+	tree := New()
+	tree.Insert("example.com", 1)
+	tree.Insert("sub.example.com", 2)
+	tree.Insert("*.example.com", 3)
+
+	visited := []int{}
+	exacts := []bool{}
+	tree.Walk("sub.example.com", func(data interface{}, exact bool) {
+		visited = append(visited, data.(int))
+		exacts = append(exacts, exact)
+	})
+
+	if len(visited) != 2 || visited[0] != 1 || visited[1] != 2 {
+		t.Errorf("unexpected walk: %v", visited)
+	}
+
+	if exacts[0] || !exacts[1] {
+		t.Errorf("unexpected exact flags: %v", exacts)
+	}
+}
@@ -0,0 +1,187 @@
+package geosite
+
+import (
+	"errors"
+	"strings"
+)
+
+// the geosite file is a protobuf encoded GeoSiteList of v2ray,
+// only the fields used by clash are decoded
+//
+// message GeoSiteList { repeated GeoSite entry = 1; }
+// message GeoSite { string country_code = 1; repeated Domain domain = 2; }
+// message Domain { Type type = 1; string value = 2; repeated Attribute attribute = 3; }
+// message Attribute { string key = 1; oneof typed_value { bool bool_value = 2; int64 int_value = 3; } }
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformed = errors.New("malformed geosite data")
+
+type reader struct {
+	buf []byte
+}
+
+func (r *reader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *reader) varint() (uint64, error) {
+	var x uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if len(r.buf) == 0 {
+			return 0, errMalformed
+		}
+		b := r.buf[0]
+		r.buf = r.buf[1:]
+		x |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return x, nil
+		}
+	}
+	return 0, errMalformed
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < l {
+		return nil, errMalformed
+	}
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b, nil
+}
+
+func (r *reader) skip(wireType uint64) error {
+	var n int
+	switch wireType {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed64:
+		n = 8
+	case wireFixed32:
+		n = 4
+	default:
+		return errMalformed
+	}
+
+	if len(r.buf) < n {
+		return errMalformed
+	}
+	r.buf = r.buf[n:]
+	return nil
+}
+
+// field reads the next field, bytes fields are returned as value,
+// other wire types are skipped and reported with a nil value
+func (r *reader) field() (num uint64, value []byte, varint uint64, err error) {
+	key, err := r.varint()
+	if err != nil {
+		return
+	}
+
+	num = key >> 3
+	switch wireType := key & 7; wireType {
+	case wireBytes:
+		value, err = r.bytes()
+	case wireVarint:
+		varint, err = r.varint()
+	default:
+		err = r.skip(wireType)
+	}
+	return
+}
+
+// decodeIndex maps the country code of each GeoSite to its encoded message
+func decodeIndex(buf []byte) (map[string][]byte, error) {
+	index := map[string][]byte{}
+	r := &reader{buf: buf}
+	for !r.done() {
+		num, entry, _, err := r.field()
+		if err != nil {
+			return nil, err
+		}
+		if num != 1 || entry == nil {
+			continue
+		}
+
+		name, err := decodeKey(entry)
+		if err != nil {
+			return nil, err
+		}
+		index[strings.ToUpper(name)] = entry
+	}
+	return index, nil
+}
+
+// decodeKey reads field 1, which is the country code of GeoSite and the key of Attribute
+func decodeKey(buf []byte) (string, error) {
+	r := &reader{buf: buf}
+	for !r.done() {
+		num, value, _, err := r.field()
+		if err != nil {
+			return "", err
+		}
+		if num == 1 {
+			return string(value), nil
+		}
+	}
+	return "", errMalformed
+}
+
+func decodeDomains(buf []byte) ([]Domain, error) {
+	domains := []Domain{}
+	r := &reader{buf: buf}
+	for !r.done() {
+		num, value, _, err := r.field()
+		if err != nil {
+			return nil, err
+		}
+		if num != 2 || value == nil {
+			continue
+		}
+
+		domain, err := decodeDomain(value)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+	return domains, nil
+}
+
+func decodeDomain(buf []byte) (Domain, error) {
+	domain := Domain{}
+	r := &reader{buf: buf}
+	for !r.done() {
+		num, value, varint, err := r.field()
+		if err != nil {
+			return domain, err
+		}
+
+		switch num {
+		case 1:
+			domain.Type = DomainType(varint)
+		case 2:
+			domain.Value = string(value)
+		case 3:
+			key, err := decodeKey(value)
+			if err != nil {
+				return domain, err
+			}
+			domain.Attributes = append(domain.Attributes, key)
+		}
+	}
+	return domain, nil
+}
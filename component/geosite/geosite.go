@@ -0,0 +1,197 @@
+package geosite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	trie "github.com/Dreamacro/clash/component/domain-trie"
+	"github.com/Dreamacro/clash/log"
+)
+
+// DomainType is the way a domain of a geosite category matches
+type DomainType int
+
+const (
+	Plain DomainType = iota
+	Regex
+	RootDomain
+	Full
+)
+
+type Domain struct {
+	Type       DomainType
+	Value      string
+	Attributes []string
+}
+
+func (d *Domain) hasAttribute(attr string) bool {
+	for _, a := range d.Attributes {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// Matcher matches domains of a geosite category
+type Matcher struct {
+	domains  *trie.Trie
+	keywords []string
+	regexes  []*regexp.Regexp
+	count    int
+}
+
+func (m *Matcher) Match(domain string) bool {
+	if m.domains.SearchSuffix(domain) != nil {
+		return true
+	}
+
+	for _, keyword := range m.keywords {
+		if strings.Contains(domain, keyword) {
+			return true
+		}
+	}
+
+	for _, regex := range m.regexes {
+		if regex.MatchString(domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count returns the number of domains in the category
+func (m *Matcher) Count() int {
+	return m.count
+}
+
+func newMatcher(domains []Domain) *Matcher {
+	m := &Matcher{domains: trie.New()}
+	for _, domain := range domains {
+		value := strings.ToLower(domain.Value)
+
+		var err error
+		switch domain.Type {
+		case Plain:
+			m.keywords = append(m.keywords, value)
+		case Regex:
+			var regex *regexp.Regexp
+			if regex, err = regexp.Compile(domain.Value); err == nil {
+				m.regexes = append(m.regexes, regex)
+			}
+		case RootDomain:
+			err = m.domains.Insert("+."+value, true)
+		case Full:
+			err = m.domains.Insert(value, true)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Debugln("[GeoSite] ignore domain %s: %s", domain.Value, err.Error())
+			continue
+		}
+		m.count++
+	}
+
+	return m
+}
+
+// indexTTL is how long the decoded file is kept after its last use, the rules
+// of a config are parsed in a row so the file is read once per parse
+var indexTTL = 10 * time.Second
+
+// cachedIndex is the index of a file as it was at modTime
+type cachedIndex struct {
+	path    string
+	modTime time.Time
+	size    int64
+	index   map[string][]byte
+}
+
+var (
+	mux     sync.Mutex
+	cached  *cachedIndex
+	release *time.Timer
+)
+
+func loadIndex(file string) (map[string][]byte, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if release == nil {
+		release = time.AfterFunc(indexTTL, dropIndex)
+	} else {
+		release.Reset(indexTTL)
+	}
+
+	if cached != nil && cached.path == file && cached.modTime.Equal(stat.ModTime()) && cached.size == stat.Size() {
+		return cached.index, nil
+	}
+
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := decodeIndex(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	cached = &cachedIndex{path: file, modTime: stat.ModTime(), size: stat.Size(), index: idx}
+	return idx, nil
+}
+
+func dropIndex() {
+	mux.Lock()
+	cached = nil
+	mux.Unlock()
+}
+
+// Load builds the Matcher of category from the geosite file,
+// `category@attr` only keeps the domains having the attribute attr
+func Load(file, category string) (*Matcher, error) {
+	index, err := loadIndex(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't load geosite: %w", err)
+	}
+
+	name, attr := strings.ToUpper(category), ""
+	if i := strings.Index(name, "@"); i != -1 {
+		name, attr = name[:i], strings.ToLower(name[i+1:])
+	}
+
+	entry, ok := index[name]
+	if !ok {
+		return nil, fmt.Errorf("geosite category %s not found", category)
+	}
+
+	domains, err := decodeDomains(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if attr != "" {
+		filtered := domains[:0]
+		for _, domain := range domains {
+			if domain.hasAttribute(attr) {
+				filtered = append(filtered, domain)
+			}
+		}
+		domains = filtered
+	}
+
+	return newMatcher(domains), nil
+}
@@ -0,0 +1,126 @@
+package geosite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func appendVarint(buf []byte, x uint64) []byte {
+	for x >= 0x80 {
+		buf = append(buf, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(buf, byte(x))
+}
+
+func appendBytes(buf []byte, num uint64, value []byte) []byte {
+	buf = appendVarint(buf, num<<3|wireBytes)
+	buf = appendVarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func encodeDomain(tp DomainType, value string, attrs ...string) []byte {
+	buf := appendVarint(nil, 1<<3|wireVarint)
+	buf = appendVarint(buf, uint64(tp))
+	buf = appendBytes(buf, 2, []byte(value))
+	for _, attr := range attrs {
+		// bool_value = true
+		attribute := appendBytes(nil, 1, []byte(attr))
+		attribute = append(attribute, 2<<3|wireVarint, 1)
+		buf = appendBytes(buf, 3, attribute)
+	}
+	return buf
+}
+
+func encodeSite(code string, domains ...[]byte) []byte {
+	buf := appendBytes(nil, 1, []byte(code))
+	for _, domain := range domains {
+		buf = appendBytes(buf, 2, domain)
+	}
+	return buf
+}
+
+func writeGeoSite(t *testing.T) string {
+	list := appendBytes(nil, 1, encodeSite("GOOGLE",
+		encodeDomain(RootDomain, "google.com"),
+		encodeDomain(Full, "www.google.cn", "cn"),
+		encodeDomain(Plain, "googleapis"),
+		encodeDomain(Regex, `^gstatic\d+\.net$`),
+	))
+	list = appendBytes(list, 1, encodeSite("CN", encodeDomain(RootDomain, "cn")))
+
+	dir, err := ioutil.TempDir("", "geosite")
+	assert.Nil(t, err)
+	file := filepath.Join(dir, "geosite.dat")
+	assert.Nil(t, ioutil.WriteFile(file, list, 0644))
+	return file
+}
+
+func TestGeoSite_Load(t *testing.T) {
+	file := writeGeoSite(t)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	m, err := Load(file, "google")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, m.Count())
+	assert.True(t, m.Match("google.com"))
+	assert.True(t, m.Match("mail.google.com"))
+	assert.True(t, m.Match("www.google.cn"))
+	assert.True(t, m.Match("fonts.googleapis.com"))
+	assert.True(t, m.Match("gstatic1.net"))
+	assert.False(t, m.Match("google.cn"))
+	assert.False(t, m.Match("notgoogle.com"))
+
+	m, err = Load(file, "GOOGLE@cn")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, m.Count())
+	assert.True(t, m.Match("www.google.cn"))
+	assert.False(t, m.Match("google.com"))
+
+	m, err = Load(file, "cn")
+	assert.Nil(t, err)
+	assert.True(t, m.Match("baidu.cn"))
+
+	_, err = Load(file, "unknown")
+	assert.NotNil(t, err)
+}
+
+func TestGeoSite_Reload(t *testing.T) {
+	file := writeGeoSite(t)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	_, err := Load(file, "google")
+	assert.Nil(t, err)
+
+	// an updated file is read again
+	list := appendBytes(nil, 1, encodeSite("GOOGLE", encodeDomain(Full, "google.com")))
+	assert.Nil(t, ioutil.WriteFile(file, list, 0644))
+	m, err := Load(file, "google")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, m.Count())
+
+	// and dropped once it isn't used
+	indexTTL = 10 * time.Millisecond
+	defer func() { indexTTL = 10 * time.Second }()
+	dropIndex()
+	_, err = Load(file, "google")
+	assert.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	mux.Lock()
+	assert.Nil(t, cached)
+	mux.Unlock()
+}
+
+func TestGeoSite_Malformed(t *testing.T) {
+	_, err := decodeIndex([]byte{1<<3 | wireBytes, 10, 1})
+	assert.NotNil(t, err)
+
+	_, err = decodeIndex([]byte{0x80})
+	assert.NotNil(t, err)
+}
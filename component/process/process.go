@@ -0,0 +1,31 @@
+package process
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	ErrInvalidNetwork     = errors.New("invalid network")
+	ErrPlatformNotSupport = errors.New("not support on this platform")
+	ErrNotFound           = errors.New("process not found")
+)
+
+const (
+	TCP = "tcp"
+	UDP = "udp"
+)
+
+// FindProcessPath returns the executable path of the local process that owns
+// the socket bound to srcIP:srcPort
+func FindProcessPath(network string, srcIP net.IP, srcPort int) (string, error) {
+	if network != TCP && network != UDP {
+		return "", ErrInvalidNetwork
+	}
+
+	if srcIP == nil {
+		return "", ErrNotFound
+	}
+
+	return findProcessPath(network, srcIP, srcPort)
+}
@@ -0,0 +1,233 @@
+package process
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/Dreamacro/clash/common/cache"
+)
+
+const (
+	// pathCacheAge is the time (second) the process of a socket is kept,
+	// an udp socket is looked up for every session it opens
+	pathCacheAge  = 60
+	pathCacheSize = 1024
+
+	maxRecentPids = 16
+)
+
+var (
+	pathCache = cache.NewLRUCache(cache.WithAge(pathCacheAge), cache.WithSize(pathCacheSize))
+
+	// recent holds the processes the latest sockets belong to, they are
+	// searched first since a process usually opens many connections
+	recent = &pidList{}
+)
+
+// /proc/net/{tcp,udp}{,6} print addresses as host order 32-bit words
+var nativeEndian = func() binary.ByteOrder {
+	var x uint16 = 1
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// pidList is a short most-recently-used list of pids
+type pidList struct {
+	mux  sync.Mutex
+	pids []string
+}
+
+func (pl *pidList) list() []string {
+	pl.mux.Lock()
+	defer pl.mux.Unlock()
+	return append([]string{}, pl.pids...)
+}
+
+func (pl *pidList) add(pid string) {
+	pl.mux.Lock()
+	defer pl.mux.Unlock()
+
+	pids := []string{pid}
+	for _, p := range pl.pids {
+		if p != pid && len(pids) < maxRecentPids {
+			pids = append(pids, p)
+		}
+	}
+	pl.pids = pids
+}
+
+func findProcessPath(network string, srcIP net.IP, srcPort int) (string, error) {
+	inode, uid, err := findSocketInode(network, srcIP, srcPort)
+	if err != nil {
+		return "", err
+	}
+
+	if path, ok := pathCache.Get(inode); ok {
+		return path.(string), nil
+	}
+
+	path, err := findProcessPathByInode(inode, uid)
+	if err != nil {
+		return "", err
+	}
+	pathCache.Set(inode, path)
+	return path, nil
+}
+
+// findSocketInode returns the inode of the socket and the uid owning it
+func findSocketInode(network string, srcIP net.IP, srcPort int) (string, uint32, error) {
+	// dual-stack sockets show IPv4 address as IPv4-mapped IPv6 address in the v6 table
+	for _, path := range []string{"/proc/net/" + network, "/proc/net/" + network + "6"} {
+		inode, uid, err := searchSocketTable(path, network, srcIP, srcPort)
+		if err == nil {
+			return inode, uid, nil
+		}
+	}
+
+	return "", 0, ErrNotFound
+}
+
+func searchSocketTable(path string, network string, srcIP net.IP, srcPort int) (string, uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// skip the header line
+	scanner.Scan()
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		ip, port, err := parseSocketAddr(fields[1])
+		if err != nil || port != srcPort {
+			continue
+		}
+
+		// udp socket could be bound to an unspecified address
+		if !ip.Equal(srcIP) && !(network == UDP && ip.IsUnspecified()) {
+			continue
+		}
+
+		uid, err := strconv.ParseUint(fields[7], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		if inode := fields[9]; inode != "0" {
+			return inode, uint32(uid), nil
+		}
+	}
+
+	return "", 0, ErrNotFound
+}
+
+// parseSocketAddr parses address like 0100007F:1F90
+func parseSocketAddr(s string) (net.IP, int, error) {
+	i := strings.IndexByte(s, ':')
+	if i == -1 {
+		return nil, 0, ErrNotFound
+	}
+
+	buf, err := hex.DecodeString(s[:i])
+	if err != nil || (len(buf) != net.IPv4len && len(buf) != net.IPv6len) {
+		return nil, 0, ErrNotFound
+	}
+
+	ip := make(net.IP, len(buf))
+	for j := 0; j < len(buf); j += 4 {
+		nativeEndian.PutUint32(ip[j:], binary.BigEndian.Uint32(buf[j:]))
+	}
+
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return ip, int(port), nil
+}
+
+// findProcessPathByInode searches the fds of the recent processes first, then
+// the ones of the processes run by uid. A process which isn't dumpable shows
+// its /proc entry as owned by root, so those are searched as well.
+func findProcessPathByInode(inode string, uid uint32) (string, error) {
+	target := "socket:[" + inode + "]"
+
+	for _, pid := range recent.list() {
+		if ownsSocket(pid, target) {
+			return processPath(pid)
+		}
+	}
+
+	proc, err := os.Open("/proc")
+	if err != nil {
+		return "", err
+	}
+	pids, err := proc.Readdirnames(-1)
+	proc.Close()
+	if err != nil {
+		return "", err
+	}
+
+	for _, pid := range pids {
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+
+		stat, err := os.Stat(filepath.Join("/proc", pid))
+		if err != nil {
+			continue
+		}
+		if sys, ok := stat.Sys().(*syscall.Stat_t); ok && sys.Uid != uid && sys.Uid != 0 {
+			continue
+		}
+
+		if ownsSocket(pid, target) {
+			return processPath(pid)
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func processPath(pid string) (string, error) {
+	recent.add(pid)
+	return os.Readlink(filepath.Join("/proc", pid, "exe"))
+}
+
+// ownsSocket reports whether one of the fds of pid links to target
+func ownsSocket(pid string, target string) bool {
+	fdDir := filepath.Join("/proc", pid, "fd")
+	dir, err := os.Open(fdDir)
+	if err != nil {
+		return false
+	}
+	fds, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return false
+	}
+
+	for _, fd := range fds {
+		if link, err := os.Readlink(filepath.Join(fdDir, fd)); err == nil && link == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package process
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestParseSocketAddr(t *testing.T) {
+	ip, port, err := parseSocketAddr("0100007F:1F90")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nativeEndian.Uint16([]byte{1, 0}) == 1 && !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("unexpected ip %s", ip)
+	}
+
+	if port != 8080 {
+		t.Errorf("unexpected port %d", port)
+	}
+}
+
+func TestPidList(t *testing.T) {
+	pl := &pidList{}
+	for i := 0; i < maxRecentPids+2; i++ {
+		pl.add(strconv.Itoa(i))
+	}
+	pl.add("5")
+
+	pids := pl.list()
+	if len(pids) != maxRecentPids || pids[0] != "5" || pids[1] != strconv.Itoa(maxRecentPids+1) {
+		t.Errorf("unexpected pids %v", pids)
+	}
+}
+
+func TestFindProcessPath_Cache(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	addr := pc.LocalAddr().(*net.UDPAddr)
+	path, err := FindProcessPath(UDP, addr.IP, addr.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inode, _, err := findSocketInode(UDP, addr.IP, addr.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, ok := pathCache.Get(inode); !ok || cached != path {
+		t.Errorf("expected %s cached, got %v", path, cached)
+	}
+	if pids := recent.list(); len(pids) == 0 || pids[0] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected pid %d first, got %v", os.Getpid(), pids)
+	}
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestFindProcessPath_TCP(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only supported on linux")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			defer c.Close()
+		}
+	}()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	addr := c.LocalAddr().(*net.TCPAddr)
+	path, err := FindProcessPath(TCP, addr.IP, addr.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exe, _ := os.Executable()
+	if path != exe {
+		t.Errorf("expected %s, got %s", exe, path)
+	}
+}
+
+func TestFindProcessPath_UDP(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only supported on linux")
+	}
+
+	pc, err := net.ListenPacket("udp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+	p, _ := strconv.Atoi(port)
+	path, err := FindProcessPath(UDP, net.ParseIP("127.0.0.1"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exe, _ := os.Executable()
+	if path != exe {
+		t.Errorf("expected %s, got %s", exe, path)
+	}
+}
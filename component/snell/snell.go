@@ -15,15 +15,29 @@ import (
 const (
 	CommandPing    byte = 0
 	CommandConnect byte = 1
+	CommandUDP     byte = 6
 
 	CommandTunnel byte = 0
 	CommandError  byte = 2
 
+	CommandUDPForward byte = 1
+
 	Version byte = 1
+
+	Version1 = 1
+	Version2 = 2
+	Version3 = 3
+
+	// maxPacketSize is the max payload of an aead chunk, every udp packet
+	// is sent in a single chunk
+	maxPacketSize = 0x3FFF
 )
 
 var (
 	bufferPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+	errPacketTooLarge = errors.New("packet too large")
+	errInvalidPacket  = errors.New("invalid packet")
 )
 
 type Snell struct {
@@ -63,6 +77,8 @@ func (s *Snell) Read(b []byte) (int, error) {
 	return 0, errors.New(string(msg))
 }
 
+// WriteHeader requests a tcp tunnel, it sends CommandConnect for every version
+// since the v2 sessions end with a zero chunk instead of closing the conn
 func WriteHeader(conn net.Conn, host string, port uint) error {
 	buf := bufferPool.Get().(*bytes.Buffer)
 	buf.Reset()
@@ -89,3 +105,80 @@ func StreamConn(conn net.Conn, psk []byte) net.Conn {
 	cipher := &snellCipher{psk, chacha20poly1305.New}
 	return &Snell{Conn: shadowaead.NewConn(conn, cipher)}
 }
+
+// WriteUDPHeader starts an udp session over conn, which is only supported
+// since v3
+func WriteUDPHeader(conn net.Conn, version int) error {
+	if version < Version3 {
+		return errors.New("udp is only supported by snell v3")
+	}
+
+	// version, command & clientID length
+	_, err := conn.Write([]byte{Version, CommandUDP, 0})
+	return err
+}
+
+// WritePacket sends payload to host:port over an udp session, host is
+// either a domain or an ip
+func WritePacket(w io.Writer, host string, port uint, payload []byte) (int, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	buf.WriteByte(CommandUDPForward)
+
+	if ip := net.ParseIP(host); ip == nil {
+		buf.WriteByte(uint8(len(host)))
+		buf.WriteString(host)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		buf.Write([]byte{0, 4})
+		buf.Write(ip4)
+	} else {
+		buf.Write([]byte{0, 6})
+		buf.Write(ip.To16())
+	}
+	binary.Write(buf, binary.BigEndian, uint16(port))
+	buf.Write(payload)
+
+	if buf.Len() > maxPacketSize {
+		return 0, errPacketTooLarge
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(payload), nil
+}
+
+// ReadPacket reads a packet of an udp session into payload, r must return a
+// whole aead chunk on every read
+func ReadPacket(r io.Reader, payload []byte) (net.Addr, int, error) {
+	buf := make([]byte, 65535)
+	n, err := r.Read(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf = buf[:n]
+
+	// ip type, ip & port
+	if len(buf) < 1 {
+		return nil, 0, errInvalidPacket
+	}
+	var ipLen int
+	switch buf[0] {
+	case 4:
+		ipLen = net.IPv4len
+	case 6:
+		ipLen = net.IPv6len
+	default:
+		return nil, 0, errInvalidPacket
+	}
+	if len(buf) < 1+ipLen+2 {
+		return nil, 0, errInvalidPacket
+	}
+
+	addr := &net.UDPAddr{
+		IP:   net.IP(append([]byte{}, buf[1:1+ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(buf[1+ipLen:])),
+	}
+	return addr, copy(payload, buf[1+ipLen+2:]), nil
+}
@@ -0,0 +1,107 @@
+package snell
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Dreamacro/go-shadowsocks2/shadowaead"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// serveUDP answers every packet from 1.2.3.4:53 with the same payload
+func serveUDP(conn net.Conn, psk []byte, requests chan<- []byte) {
+	c := shadowaead.NewConn(conn, &snellCipher{psk, chacha20poly1305.New})
+	defer c.Close()
+
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(c, header); err != nil {
+		return
+	}
+	requests <- header
+	if _, err := c.Write([]byte{CommandTunnel}); err != nil {
+		return
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return
+		}
+		requests <- append([]byte{}, buf[:n]...)
+
+		// skip the command, the address & the port
+		packet := buf[:n]
+		var payload []byte
+		switch {
+		case packet[1] != 0:
+			payload = packet[2+int(packet[1])+2:]
+		case packet[2] == 4:
+			payload = packet[3+net.IPv4len+2:]
+		default:
+			payload = packet[3+net.IPv6len+2:]
+		}
+
+		reply := append([]byte{4, 1, 2, 3, 4, 0, 53}, payload...)
+		if _, err := c.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func TestUDP(t *testing.T) {
+	psk := []byte("psk")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+	requests := make(chan []byte, 1)
+	go func() {
+		server, err := l.Accept()
+		if err == nil {
+			serveUDP(server, psk, requests)
+		}
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	c := StreamConn(client, psk)
+	defer c.Close()
+
+	assert.NotNil(t, WriteUDPHeader(c, Version2))
+	assert.Nil(t, WriteUDPHeader(c, Version3))
+	assert.Equal(t, []byte{Version, CommandUDP, 0}, <-requests)
+
+	cases := []struct {
+		host    string
+		request []byte
+	}{
+		{"example.org", append([]byte{CommandUDPForward, 11}, "example.org\x00\x35"...)},
+		{"1.2.3.4", []byte{CommandUDPForward, 0, 4, 1, 2, 3, 4, 0, 0x35}},
+		{"::1", append(append([]byte{CommandUDPForward, 0, 6}, net.IPv6loopback...), 0, 0x35)},
+	}
+
+	buf := make([]byte, 1024)
+	for _, tc := range cases {
+		msg := []byte("hello snell")
+		n, err := WritePacket(c, tc.host, 53, msg)
+		assert.Nil(t, err, tc.host)
+		assert.Equal(t, len(msg), n, tc.host)
+		assert.Equal(t, append(tc.request, msg...), <-requests, tc.host)
+
+		addr, n, err := ReadPacket(c, buf)
+		assert.Nil(t, err, tc.host)
+		assert.Equal(t, "1.2.3.4:53", addr.String(), tc.host)
+		assert.Equal(t, msg, buf[:n], tc.host)
+	}
+
+	_, err = WritePacket(c, "1.2.3.4", 53, bytes.Repeat([]byte{0}, maxPacketSize))
+	assert.Equal(t, errPacketTooLarge, err)
+}
@@ -263,6 +263,11 @@ func ClientHandshake(rw io.ReadWriter, addr Addr, command Command, user *User) (
 	return readAddr(rw, buf)
 }
 
+// ReadAddr reads a SOCKS address from r, b must be at least MaxAddrLen bytes
+func ReadAddr(r io.Reader, b []byte) (Addr, error) {
+	return readAddr(r, b)
+}
+
 func readAddr(r io.Reader, b []byte) (Addr, error) {
 	if len(b) < MaxAddrLen {
 		return nil, io.ErrShortBuffer
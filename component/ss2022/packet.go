@@ -0,0 +1,208 @@
+package ss2022
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// separate header of the aes methods: session id | packet id
+	separateHeaderSize = 8 + 8
+	// type | timestamp | padding length
+	clientHeaderSize = 1 + 8 + 2
+	// type | timestamp | client session id | padding length
+	serverHeaderSize = 1 + 8 + 8 + 2
+)
+
+// packetConn is the client side of a shadowsocks 2022 udp session, every
+// packet carries the socks address followed by the payload like the legacy
+// packet ciphers
+type packetConn struct {
+	net.PacketConn
+	method *Method
+
+	sessionID uint64
+	packetID  uint64
+	session   cipher.AEAD
+
+	mux             sync.Mutex
+	serverSessionID uint64
+	serverSession   cipher.AEAD
+	filter          *slidingWindow
+}
+
+func newPacketConn(pc net.PacketConn, m *Method) *packetConn {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	c := &packetConn{
+		PacketConn: pc,
+		method:     m,
+		sessionID:  binary.BigEndian.Uint64(id),
+	}
+	if !m.chacha {
+		c.session, _ = m.newAEAD(m.sessionKey(id))
+	}
+	return c
+}
+
+func (pc *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	packetID := atomic.AddUint64(&pc.packetID, 1) - 1
+
+	header := make([]byte, separateHeaderSize+clientHeaderSize)
+	binary.BigEndian.PutUint64(header, pc.sessionID)
+	binary.BigEndian.PutUint64(header[8:], packetID)
+	header[16] = HeaderTypeClient
+	putTimestamp(header[17:25])
+	// no padding
+
+	var packet []byte
+	if pc.method.chacha {
+		nonce := make([]byte, pc.method.udpAEAD.NonceSize())
+		rand.Read(nonce)
+		plaintext := append(header, b...)
+		packet = pc.method.udpAEAD.Seal(nonce, nonce, plaintext, nil)
+	} else {
+		separate := header[:separateHeaderSize]
+		plaintext := append(header[separateHeaderSize:], b...)
+
+		packet = make([]byte, separateHeaderSize, separateHeaderSize+len(plaintext)+tagSize)
+		pc.method.block.Encrypt(packet, separate)
+		packet = pc.session.Seal(packet, separate[4:16], plaintext, nil)
+	}
+
+	if _, err := pc.PacketConn.WriteTo(packet, addr); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (pc *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := pc.PacketConn.ReadFrom(b)
+		if err != nil {
+			return n, addr, err
+		}
+
+		payload, err := pc.open(b[:n])
+		if err != nil {
+			// drop the invalid packets like the legacy ciphers
+			continue
+		}
+		return copy(b, payload), addr, nil
+	}
+}
+
+// open decrypts a server packet and return its payload
+func (pc *packetConn) open(packet []byte) ([]byte, error) {
+	var separate, plaintext []byte
+	var session cipher.AEAD
+	if pc.method.chacha {
+		nonceSize := pc.method.udpAEAD.NonceSize()
+		if len(packet) < nonceSize+separateHeaderSize+serverHeaderSize+tagSize {
+			return nil, ErrShortPacket
+		}
+
+		buf, err := pc.method.udpAEAD.Open(nil, packet[:nonceSize], packet[nonceSize:], nil)
+		if err != nil {
+			return nil, err
+		}
+		separate, plaintext = buf[:separateHeaderSize], buf[separateHeaderSize:]
+	} else {
+		if len(packet) < separateHeaderSize+serverHeaderSize+tagSize {
+			return nil, ErrShortPacket
+		}
+
+		separate = make([]byte, separateHeaderSize)
+		pc.method.block.Decrypt(separate, packet[:separateHeaderSize])
+
+		var err error
+		session, err = pc.serverAEAD(separate[:8])
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = session.Open(nil, separate[4:16], packet[separateHeaderSize:], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if plaintext[0] != HeaderTypeServer {
+		return nil, ErrBadHeaderType
+	}
+	if !validTimestamp(binary.BigEndian.Uint64(plaintext[1:9])) {
+		return nil, ErrBadTimestamp
+	}
+	if binary.BigEndian.Uint64(plaintext[9:17]) != pc.sessionID {
+		return nil, ErrBadSession
+	}
+
+	paddingLen := int(binary.BigEndian.Uint16(plaintext[17:19]))
+	if len(plaintext) < serverHeaderSize+paddingLen {
+		return nil, ErrShortPacket
+	}
+
+	if !pc.accept(binary.BigEndian.Uint64(separate[:8]), binary.BigEndian.Uint64(separate[8:]), session) {
+		return nil, ErrPacketReplay
+	}
+
+	return plaintext[serverHeaderSize+paddingLen:], nil
+}
+
+// serverAEAD return the aead of a server session, a new session id resets it
+func (pc *packetConn) serverAEAD(sessionID []byte) (cipher.AEAD, error) {
+	pc.mux.Lock()
+	defer pc.mux.Unlock()
+
+	id := binary.BigEndian.Uint64(sessionID)
+	if pc.serverSession != nil && pc.serverSessionID == id {
+		return pc.serverSession, nil
+	}
+	return pc.method.newAEAD(pc.method.sessionKey(sessionID))
+}
+
+// accept checks the packet id against the window of the server session,
+// switching to the session when it is new
+func (pc *packetConn) accept(sessionID, packetID uint64, session cipher.AEAD) bool {
+	pc.mux.Lock()
+	defer pc.mux.Unlock()
+
+	if pc.filter == nil || pc.serverSessionID != sessionID {
+		pc.serverSessionID = sessionID
+		pc.serverSession = session
+		pc.filter = &slidingWindow{}
+	}
+	return pc.filter.check(packetID)
+}
+
+const windowSize = 64
+
+// slidingWindow rejects the packet ids seen before or too old
+type slidingWindow struct {
+	last   uint64
+	bitmap uint64
+}
+
+func (w *slidingWindow) check(id uint64) bool {
+	if id > w.last {
+		diff := id - w.last
+		if diff >= windowSize {
+			w.bitmap = 1
+		} else {
+			w.bitmap = w.bitmap<<diff | 1
+		}
+		w.last = id
+		return true
+	}
+
+	diff := w.last - id
+	if diff >= windowSize || w.bitmap&(1<<diff) != 0 {
+		return false
+	}
+	w.bitmap |= 1 << diff
+	return true
+}
@@ -0,0 +1,192 @@
+package ss2022
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/blake3"
+)
+
+// Header types
+const (
+	HeaderTypeClient byte = 0
+	HeaderTypeServer byte = 1
+)
+
+const (
+	// MaxPaddingLength of the request header without initial payload
+	MaxPaddingLength = 900
+	// MaxPayloadSize of a chunk
+	MaxPayloadSize = 0xFFFF
+
+	tagSize            = 16
+	timestampTolerance = 30 * time.Second
+	saltTTL            = 60 * time.Second
+	subkeyContext      = "shadowsocks 2022 session subkey"
+)
+
+var (
+	ErrBadTimestamp  = errors.New("ss2022: timestamp out of range")
+	ErrBadHeaderType = errors.New("ss2022: unexpected header type")
+	ErrBadSalt       = errors.New("ss2022: request salt mismatch")
+	ErrReplayedSalt  = errors.New("ss2022: salt replayed")
+	ErrPacketReplay  = errors.New("ss2022: packet replayed")
+	ErrBadSession    = errors.New("ss2022: unexpected session")
+	ErrShortPacket   = errors.New("ss2022: short packet")
+)
+
+// methods maps the method names to their key size
+var methods = map[string]int{
+	"2022-blake3-aes-128-gcm":       16,
+	"2022-blake3-aes-256-gcm":       32,
+	"2022-blake3-chacha20-poly1305": 32,
+}
+
+// IsMethod reports whether name is a shadowsocks 2022 method
+func IsMethod(name string) bool {
+	_, ok := methods[name]
+	return ok
+}
+
+// Method is a shadowsocks 2022 cipher, it implements core.Cipher of go-shadowsocks2
+type Method struct {
+	name    string
+	psk     []byte
+	chacha  bool
+	block   cipher.Block
+	udpAEAD cipher.AEAD
+	salts   *saltPool
+}
+
+// New return a Method with the base64 encoded pre-shared key
+func New(name, password string) (*Method, error) {
+	keySize, ok := methods[name]
+	if !ok {
+		return nil, fmt.Errorf("ss2022: unknown method %s", name)
+	}
+
+	psk, err := base64.StdEncoding.DecodeString(password)
+	if err != nil {
+		return nil, fmt.Errorf("ss2022: decode psk error: %w", err)
+	}
+	if len(psk) != keySize {
+		return nil, fmt.Errorf("ss2022: psk of %s must be %d bytes, got %d", name, keySize, len(psk))
+	}
+
+	m := &Method{
+		name:  name,
+		psk:   psk,
+		salts: newSaltPool(),
+	}
+
+	if name == "2022-blake3-chacha20-poly1305" {
+		m.chacha = true
+		m.udpAEAD, err = chacha20poly1305.NewX(psk)
+	} else {
+		m.block, err = aes.NewCipher(psk)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// KeySize return the length of the pre-shared key and the salt
+func (m *Method) KeySize() int {
+	return len(m.psk)
+}
+
+// StreamConn implements core.StreamConnCipher
+func (m *Method) StreamConn(conn net.Conn) net.Conn {
+	return &streamConn{Conn: conn, method: m}
+}
+
+// PacketConn implements core.PacketConnCipher
+func (m *Method) PacketConn(pc net.PacketConn) net.PacketConn {
+	return newPacketConn(pc, m)
+}
+
+// sessionKey derives the session subkey of a salt (tcp) or a session id (udp)
+func (m *Method) sessionKey(salt []byte) []byte {
+	material := make([]byte, 0, len(m.psk)+len(salt))
+	material = append(material, m.psk...)
+	material = append(material, salt...)
+
+	key := make([]byte, len(m.psk))
+	blake3.DeriveKey(key, subkeyContext, material)
+	return key
+}
+
+// newAEAD return the aead of the method with a session subkey
+func (m *Method) newAEAD(key []byte) (cipher.AEAD, error) {
+	if m.chacha {
+		return chacha20poly1305.New(key)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func validTimestamp(timestamp uint64) bool {
+	diff := time.Now().Sub(time.Unix(int64(timestamp), 0))
+	return diff < timestampTolerance && diff > -timestampTolerance
+}
+
+func putTimestamp(b []byte) {
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Unix()))
+}
+
+// increment the little-endian nonce counter
+func increment(b []byte) {
+	for i := range b {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// saltPool remembers the salts seen in the last saltTTL
+type saltPool struct {
+	mux   sync.Mutex
+	salts map[string]time.Time
+	last  time.Time
+}
+
+func newSaltPool() *saltPool {
+	return &saltPool{salts: map[string]time.Time{}}
+}
+
+// check adds salt to the pool, it return false when salt was already seen
+func (p *saltPool) check(salt []byte) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.last) > saltTTL {
+		for s, expire := range p.salts {
+			if now.After(expire) {
+				delete(p.salts, s)
+			}
+		}
+		p.last = now
+	}
+
+	if expire, ok := p.salts[string(salt)]; ok && now.Before(expire) {
+		return false
+	}
+	p.salts[string(salt)] = now.Add(saltTTL)
+	return true
+}
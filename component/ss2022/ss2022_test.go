@@ -0,0 +1,319 @@
+package ss2022
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Dreamacro/clash/component/socks5"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testMethods = []string{
+	"2022-blake3-aes-128-gcm",
+	"2022-blake3-aes-256-gcm",
+	"2022-blake3-chacha20-poly1305",
+}
+
+func newTestMethod(t *testing.T, name string) *Method {
+	psk := make([]byte, methods[name])
+	rand.Read(psk)
+	m, err := New(name, base64.StdEncoding.EncodeToString(psk))
+	assert.Nil(t, err)
+	return m
+}
+
+// aeadStream seals and opens messages with an incrementing nonce
+type aeadStream struct {
+	aead  cipher.AEAD
+	nonce []byte
+}
+
+func newAEADStream(m *Method, salt []byte) *aeadStream {
+	aead, _ := m.newAEAD(m.sessionKey(salt))
+	return &aeadStream{aead: aead, nonce: make([]byte, aead.NonceSize())}
+}
+
+func (s *aeadStream) seal(dst, b []byte) []byte {
+	dst = s.aead.Seal(dst, s.nonce, b, nil)
+	increment(s.nonce)
+	return dst
+}
+
+func (s *aeadStream) open(r io.Reader, length int) ([]byte, error) {
+	buf := make([]byte, length+tagSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	b, err := s.aead.Open(nil, s.nonce, buf, nil)
+	increment(s.nonce)
+	return b, err
+}
+
+// serveTCP is a server echoing the stream back, the response header uses
+// salt and requestSalt when they are set
+func serveTCP(m *Method, conn net.Conn, salt, requestSalt []byte, addrs chan<- string) error {
+	defer conn.Close()
+
+	reqSalt := make([]byte, m.KeySize())
+	if _, err := io.ReadFull(conn, reqSalt); err != nil {
+		return err
+	}
+	reader := newAEADStream(m, reqSalt)
+
+	fixed, err := reader.open(conn, 1+8+2)
+	if err != nil {
+		return err
+	}
+	if fixed[0] != HeaderTypeClient || !validTimestamp(binary.BigEndian.Uint64(fixed[1:9])) {
+		return errors.New("invalid request header")
+	}
+
+	header, err := reader.open(conn, int(binary.BigEndian.Uint16(fixed[9:])))
+	if err != nil {
+		return err
+	}
+	addr := socks5.SplitAddr(header)
+	paddingLen := int(binary.BigEndian.Uint16(header[len(addr):]))
+	payload := header[len(addr)+2+paddingLen:]
+	if len(payload) == 0 && paddingLen == 0 {
+		return errors.New("padding is required without payload")
+	}
+	addrs <- addr.String()
+
+	if salt == nil {
+		salt = make([]byte, m.KeySize())
+		rand.Read(salt)
+	}
+	if requestSalt == nil {
+		requestSalt = reqSalt
+	}
+	writer := newAEADStream(m, salt)
+
+	// type | timestamp | request salt | length
+	resp := make([]byte, 1+8+len(requestSalt)+2)
+	resp[0] = HeaderTypeServer
+	putTimestamp(resp[1:9])
+	copy(resp[9:], requestSalt)
+	binary.BigEndian.PutUint16(resp[9+len(requestSalt):], uint16(len(payload)))
+
+	buf := append([]byte{}, salt...)
+	buf = writer.seal(buf, resp)
+	buf = writer.seal(buf, payload)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+
+	for {
+		length, err := reader.open(conn, 2)
+		if err != nil {
+			return err
+		}
+		chunk, err := reader.open(conn, int(binary.BigEndian.Uint16(length)))
+		if err != nil {
+			return err
+		}
+
+		buf := writer.seal(nil, length)
+		buf = writer.seal(buf, chunk)
+		if _, err := conn.Write(buf); err != nil {
+			return err
+		}
+	}
+}
+
+func TestSS2022_TCP(t *testing.T) {
+	for _, name := range testMethods {
+		m := newTestMethod(t, name)
+		client, server := net.Pipe()
+		addrs := make(chan string, 1)
+		go serveTCP(m, server, nil, nil, addrs)
+
+		conn := m.StreamConn(client)
+		defer conn.Close()
+
+		_, err := conn.Write(socks5.ParseAddr("example.org:443"))
+		assert.Nil(t, err, name)
+		assert.Equal(t, "example.org:443", <-addrs, name)
+
+		for _, msg := range [][]byte{[]byte("hello ss2022"), bytes.Repeat([]byte{1}, MaxPayloadSize+100)} {
+			go conn.Write(msg)
+
+			buf := make([]byte, len(msg))
+			_, err = io.ReadFull(conn, buf)
+			assert.Nil(t, err, name)
+			assert.Equal(t, msg, buf, name)
+		}
+	}
+}
+
+func TestSS2022_TCPInitialPayload(t *testing.T) {
+	m := newTestMethod(t, "2022-blake3-aes-128-gcm")
+	client, server := net.Pipe()
+	addrs := make(chan string, 1)
+	go serveTCP(m, server, nil, nil, addrs)
+
+	conn := m.StreamConn(client)
+	defer conn.Close()
+
+	// the payload after the address is echoed in the response header
+	msg := []byte("initial payload")
+	_, err := conn.Write(append([]byte(socks5.ParseAddr("1.1.1.1:53")), msg...))
+	assert.Nil(t, err)
+	assert.Equal(t, "1.1.1.1:53", <-addrs)
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(conn, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+}
+
+func TestSS2022_TCPReplay(t *testing.T) {
+	m := newTestMethod(t, "2022-blake3-aes-256-gcm")
+	salt := make([]byte, m.KeySize())
+	rand.Read(salt)
+
+	read := func(requestSalt []byte) error {
+		client, server := net.Pipe()
+		go serveTCP(m, server, salt, requestSalt, make(chan string, 1))
+
+		conn := m.StreamConn(client)
+		defer conn.Close()
+		if _, err := conn.Write(socks5.ParseAddr("example.org:80")); err != nil {
+			return err
+		}
+
+		_, err := conn.Read(make([]byte, 1))
+		return err
+	}
+
+	// both responses use the same salt, the first one answers another request
+	assert.Equal(t, ErrBadSalt, read(make([]byte, m.KeySize())))
+	assert.Equal(t, ErrReplayedSalt, read(nil))
+}
+
+// serveUDP echoes the payload of every packet back twice as the same packet
+func serveUDP(m *Method, pc net.PacketConn) {
+	serverSessionID := make([]byte, 8)
+	rand.Read(serverSessionID)
+	session, _ := m.newAEAD(m.sessionKey(serverSessionID))
+	var packetID uint64
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var separate, plaintext []byte
+		if m.chacha {
+			nonceSize := m.udpAEAD.NonceSize()
+			b, err := m.udpAEAD.Open(nil, buf[:nonceSize], buf[nonceSize:n], nil)
+			if err != nil {
+				continue
+			}
+			separate, plaintext = b[:separateHeaderSize], b[separateHeaderSize:]
+		} else {
+			separate = make([]byte, separateHeaderSize)
+			m.block.Decrypt(separate, buf[:separateHeaderSize])
+			client, _ := m.newAEAD(m.sessionKey(separate[:8]))
+			plaintext, err = client.Open(nil, separate[4:16], buf[separateHeaderSize:n], nil)
+			if err != nil {
+				continue
+			}
+		}
+
+		if plaintext[0] != HeaderTypeClient || !validTimestamp(binary.BigEndian.Uint64(plaintext[1:9])) {
+			continue
+		}
+		paddingLen := int(binary.BigEndian.Uint16(plaintext[9:11]))
+		payload := plaintext[clientHeaderSize+paddingLen:]
+
+		header := make([]byte, separateHeaderSize+serverHeaderSize)
+		copy(header, serverSessionID)
+		binary.BigEndian.PutUint64(header[8:], packetID)
+		packetID++
+		header[16] = HeaderTypeServer
+		putTimestamp(header[17:25])
+		copy(header[25:33], separate[:8])
+
+		var packet []byte
+		if m.chacha {
+			nonce := make([]byte, m.udpAEAD.NonceSize())
+			rand.Read(nonce)
+			packet = m.udpAEAD.Seal(nonce, nonce, append(header, payload...), nil)
+		} else {
+			packet = make([]byte, separateHeaderSize)
+			m.block.Encrypt(packet, header[:separateHeaderSize])
+			packet = session.Seal(packet, header[4:16], append(header[separateHeaderSize:], payload...), nil)
+		}
+
+		pc.WriteTo(packet, addr)
+		pc.WriteTo(packet, addr)
+	}
+}
+
+func TestSS2022_UDP(t *testing.T) {
+	for _, name := range testMethods {
+		m := newTestMethod(t, name)
+		server, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer server.Close()
+		go serveUDP(m, server)
+
+		client, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+		pc := m.PacketConn(client)
+		defer pc.Close()
+
+		buf := make([]byte, 1024)
+		for _, msg := range []string{"first", "second"} {
+			packet := append([]byte(socks5.ParseAddr("1.1.1.1:53")), msg...)
+			_, err = pc.WriteTo(packet, server.LocalAddr())
+			assert.Nil(t, err, name)
+
+			// the duplicated reply of the first packet is dropped
+			n, _, err := pc.ReadFrom(buf)
+			assert.Nil(t, err, name)
+			assert.Equal(t, packet, buf[:n], name)
+		}
+	}
+}
+
+func TestSS2022_New(t *testing.T) {
+	_, err := New("2022-blake3-aes-128-gcm", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	assert.NotNil(t, err)
+
+	_, err = New("2022-blake3-aes-256-gcm", "not base64")
+	assert.NotNil(t, err)
+
+	_, err = New("aes-256-gcm", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	assert.NotNil(t, err)
+
+	assert.True(t, IsMethod("2022-blake3-chacha20-poly1305"))
+	assert.False(t, IsMethod("chacha20-ietf-poly1305"))
+}
+
+func TestSS2022_SlidingWindow(t *testing.T) {
+	w := &slidingWindow{}
+	assert.True(t, w.check(0))
+	assert.False(t, w.check(0))
+	assert.True(t, w.check(2))
+	assert.True(t, w.check(1))
+	assert.False(t, w.check(1))
+	assert.True(t, w.check(100))
+	assert.False(t, w.check(2))
+	assert.True(t, w.check(99))
+}
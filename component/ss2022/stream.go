@@ -0,0 +1,200 @@
+package ss2022
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	mrand "math/rand"
+	"net"
+
+	"github.com/Dreamacro/clash/component/socks5"
+)
+
+// streamConn is the client side of a shadowsocks 2022 tcp connection, the
+// socks address of the first Write goes to the request header
+type streamConn struct {
+	net.Conn
+	method *Method
+
+	writer      cipher.AEAD
+	wNonce      []byte
+	requestSalt []byte
+
+	reader cipher.AEAD
+	rNonce []byte
+	buf    []byte
+}
+
+func (sc *streamConn) Write(b []byte) (int, error) {
+	if sc.writer == nil {
+		if err := sc.writeRequest(b); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if _, err := sc.Conn.Write(sc.sealChunks(nil, b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeRequest sends salt | fixed-length header | variable-length header,
+// the initial payload that doesn't fit in the header follows as chunks
+func (sc *streamConn) writeRequest(b []byte) error {
+	addr := socks5.SplitAddr(b)
+	if addr == nil {
+		return errors.New("ss2022: invalid socks address")
+	}
+	payload := b[len(addr):]
+
+	salt := make([]byte, sc.method.KeySize())
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := sc.method.newAEAD(sc.method.sessionKey(salt))
+	if err != nil {
+		return err
+	}
+	sc.writer = aead
+	sc.wNonce = make([]byte, aead.NonceSize())
+	sc.requestSalt = salt
+
+	paddingLen := 0
+	if len(payload) == 0 {
+		paddingLen = mrand.Intn(MaxPaddingLength) + 1
+	}
+
+	// the variable-length header is limited by its uint16 length
+	initial := payload
+	if max := MaxPayloadSize - len(addr) - 2 - paddingLen; len(initial) > max {
+		initial = initial[:max]
+	}
+
+	header := &bytes.Buffer{}
+	header.Write(addr)
+	binary.Write(header, binary.BigEndian, uint16(paddingLen))
+	header.Write(make([]byte, paddingLen))
+	header.Write(initial)
+
+	// type | timestamp | length
+	fixed := make([]byte, 1+8+2)
+	fixed[0] = HeaderTypeClient
+	putTimestamp(fixed[1:9])
+	binary.BigEndian.PutUint16(fixed[9:], uint16(header.Len()))
+
+	buf := append([]byte{}, salt...)
+	buf = sc.seal(buf, fixed)
+	buf = sc.seal(buf, header.Bytes())
+	buf = sc.sealChunks(buf, payload[len(initial):])
+
+	_, err = sc.Conn.Write(buf)
+	return err
+}
+
+func (sc *streamConn) seal(dst, plaintext []byte) []byte {
+	dst = sc.writer.Seal(dst, sc.wNonce, plaintext, nil)
+	increment(sc.wNonce)
+	return dst
+}
+
+// sealChunks appends b as sealed length | sealed payload chunks
+func (sc *streamConn) sealChunks(dst, b []byte) []byte {
+	length := make([]byte, 2)
+	for len(b) > 0 {
+		n := len(b)
+		if n > MaxPayloadSize {
+			n = MaxPayloadSize
+		}
+
+		binary.BigEndian.PutUint16(length, uint16(n))
+		dst = sc.seal(dst, length)
+		dst = sc.seal(dst, b[:n])
+		b = b[n:]
+	}
+	return dst
+}
+
+func (sc *streamConn) Read(b []byte) (int, error) {
+	if sc.reader == nil {
+		if err := sc.readResponse(); err != nil {
+			return 0, err
+		}
+	}
+
+	for len(sc.buf) == 0 {
+		length, err := sc.open(2)
+		if err != nil {
+			return 0, err
+		}
+
+		sc.buf, err = sc.open(int(binary.BigEndian.Uint16(length)))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, sc.buf)
+	sc.buf = sc.buf[n:]
+	return n, nil
+}
+
+// readResponse reads salt | fixed-length header | first payload chunk
+func (sc *streamConn) readResponse() error {
+	if sc.requestSalt == nil {
+		return errors.New("ss2022: read before request")
+	}
+
+	salt := make([]byte, sc.method.KeySize())
+	if _, err := io.ReadFull(sc.Conn, salt); err != nil {
+		return err
+	}
+	if !sc.method.salts.check(salt) {
+		return ErrReplayedSalt
+	}
+
+	aead, err := sc.method.newAEAD(sc.method.sessionKey(salt))
+	if err != nil {
+		return err
+	}
+	sc.reader = aead
+	sc.rNonce = make([]byte, aead.NonceSize())
+
+	// type | timestamp | request salt | length
+	fixed, err := sc.open(1 + 8 + len(salt) + 2)
+	if err != nil {
+		return err
+	}
+
+	if fixed[0] != HeaderTypeServer {
+		return ErrBadHeaderType
+	}
+	if !validTimestamp(binary.BigEndian.Uint64(fixed[1:9])) {
+		return ErrBadTimestamp
+	}
+	if !bytes.Equal(fixed[9:9+len(salt)], sc.requestSalt) {
+		return ErrBadSalt
+	}
+
+	sc.buf, err = sc.open(int(binary.BigEndian.Uint16(fixed[9+len(salt):])))
+	return err
+}
+
+// open reads and decrypts a sealed message of length bytes
+func (sc *streamConn) open(length int) ([]byte, error) {
+	buf := make([]byte, length+tagSize)
+	if _, err := io.ReadFull(sc.Conn, buf); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := sc.reader.Open(buf[:0], sc.rNonce, buf, nil)
+	if err != nil {
+		return nil, err
+	}
+	increment(sc.rNonce)
+	return plaintext, nil
+}
@@ -0,0 +1,136 @@
+package ssr
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// hmacSize is the size of the truncated hmac appended to a packet
+	hmacSize   = 4
+	userIDSize = 4
+)
+
+var errInvalidPacket = errors.New("invalid packet")
+
+// Protocol is the udp part of a ssr protocol plugin. The obfs plugins only
+// apply to tcp, so udp packets are the stream cipher packets of the protocol
+// payloads
+type Protocol struct {
+	// newHash is nil for the protocols leaving the packets untouched
+	newHash func() hash.Hash
+	key     []byte
+	userID  []byte
+	userKey []byte
+}
+
+// NewProtocol returns the protocol of name, key is the key of the cipher
+func NewProtocol(name, param string, key []byte) (*Protocol, error) {
+	p := &Protocol{key: key}
+	switch strings.ToLower(name) {
+	case "origin", "verify_sha1", "auth_sha1_v4":
+		return p, nil
+	case "auth_aes128_md5":
+		p.newHash = md5.New
+	case "auth_aes128_sha1":
+		p.newHash = sha1.New
+	default:
+		return nil, fmt.Errorf("udp is not supported by protocol %s", name)
+	}
+
+	// the param of multi-user servers is `uid:password`
+	p.userID = make([]byte, userIDSize)
+	params := strings.Split(param, ":")
+	if len(params) >= 2 {
+		if uid, err := strconv.ParseUint(params[0], 10, 32); err == nil {
+			binary.LittleEndian.PutUint32(p.userID, uint32(uid))
+			h := p.newHash()
+			h.Write([]byte(params[1]))
+			p.userKey = h.Sum(nil)
+		}
+	}
+	if p.userKey == nil {
+		rand.Read(p.userID)
+		p.userKey = key
+	}
+
+	return p, nil
+}
+
+func (p *Protocol) sum(key, data []byte) []byte {
+	mac := hmac.New(p.newHash, key)
+	mac.Write(data)
+	return mac.Sum(nil)[:hmacSize]
+}
+
+// encode appends the user id and the hmac of the user to a client packet
+func (p *Protocol) encode(b []byte) []byte {
+	if p.newHash == nil {
+		return b
+	}
+
+	buf := make([]byte, 0, len(b)+userIDSize+hmacSize)
+	buf = append(buf, b...)
+	buf = append(buf, p.userID...)
+	return append(buf, p.sum(p.userKey, buf)...)
+}
+
+// decode verifies the hmac of a server packet, which is keyed by the cipher key
+func (p *Protocol) decode(b []byte) ([]byte, error) {
+	if p.newHash == nil {
+		return b, nil
+	}
+
+	if len(b) < hmacSize {
+		return nil, errInvalidPacket
+	}
+	data := b[:len(b)-hmacSize]
+	if !bytes.Equal(p.sum(p.key, data), b[len(data):]) {
+		return nil, errInvalidPacket
+	}
+	return data, nil
+}
+
+// PacketConn wraps pc, which encrypts the packets with the cipher of the
+// server, with the protocol
+func (p *Protocol) PacketConn(pc net.PacketConn) net.PacketConn {
+	return &packetConn{PacketConn: pc, protocol: p}
+}
+
+type packetConn struct {
+	net.PacketConn
+	protocol *Protocol
+}
+
+func (pc *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if _, err := pc.PacketConn.WriteTo(pc.protocol.encode(b), addr); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (pc *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := pc.PacketConn.ReadFrom(b)
+		if err != nil {
+			return n, addr, err
+		}
+
+		// drop the packets failing the verification
+		data, err := pc.protocol.decode(b[:n])
+		if err != nil {
+			continue
+		}
+		return copy(b, data), addr, nil
+	}
+}
@@ -0,0 +1,112 @@
+package ssr
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"net"
+	"testing"
+
+	"github.com/Dreamacro/go-shadowsocks2/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func truncatedHMAC(newHash func() hash.Hash, key, data []byte) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write(data)
+	return mac.Sum(nil)[:hmacSize]
+}
+
+// serveUDP verifies the packets with userKey and echoes them back twice, the
+// first reply carries a broken hmac
+func serveUDP(pc net.PacketConn, newHash func() hash.Hash, key, userKey []byte, userIDs chan<- []byte) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		packet := buf[:n]
+		if newHash != nil {
+			data := packet[:n-hmacSize]
+			if !bytes.Equal(truncatedHMAC(newHash, userKey, data), packet[len(data):]) {
+				continue
+			}
+			userIDs <- append([]byte{}, data[len(data)-userIDSize:]...)
+			packet = data[:len(data)-userIDSize]
+		}
+
+		reply := append([]byte{}, packet...)
+		if newHash != nil {
+			reply = append(reply, truncatedHMAC(newHash, key, packet)...)
+			broken := append([]byte{}, reply...)
+			broken[len(broken)-1] ^= 0xff
+			pc.WriteTo(broken, addr)
+		}
+		pc.WriteTo(reply, addr)
+	}
+}
+
+func testProtocol(t *testing.T, protocol, param string, newHash func() hash.Hash, userKey []byte, userID []byte) {
+	key := bytes.Repeat([]byte{1}, 32)
+	if userKey == nil {
+		userKey = key
+	}
+
+	ciph, err := core.PickCipher("aes-256-cfb", key, "")
+	assert.Nil(t, err)
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer server.Close()
+	userIDs := make(chan []byte, 1)
+	go serveUDP(ciph.PacketConn(server), newHash, key, userKey, userIDs)
+
+	p, err := NewProtocol(protocol, param, key)
+	assert.Nil(t, err)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	pc := p.PacketConn(ciph.PacketConn(client))
+	defer pc.Close()
+
+	msg := []byte("\x01\x01\x01\x01\x01\x00\x35hello ssr")
+	_, err = pc.WriteTo(msg, server.LocalAddr())
+	assert.Nil(t, err, protocol)
+
+	if newHash != nil {
+		id := <-userIDs
+		if userID != nil {
+			assert.Equal(t, userID, id, protocol)
+		}
+	}
+
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err, protocol)
+	assert.Equal(t, msg, buf[:n], protocol)
+}
+
+func TestProtocol(t *testing.T) {
+	testProtocol(t, "origin", "", nil, nil, nil)
+	testProtocol(t, "auth_sha1_v4", "", nil, nil, nil)
+	testProtocol(t, "auth_aes128_md5", "", md5.New, nil, nil)
+	testProtocol(t, "auth_aes128_sha1", "", sha1.New, nil, nil)
+
+	// multi-user servers
+	userKey := md5.Sum([]byte("password"))
+	userID := make([]byte, userIDSize)
+	binary.LittleEndian.PutUint32(userID, 1024)
+	testProtocol(t, "auth_aes128_md5", "1024:password", md5.New, userKey[:], userID)
+
+	_, err := NewProtocol("auth_chain_a", "", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,221 @@
+package trojan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/Dreamacro/clash/component/resolver"
+	"github.com/Dreamacro/clash/component/socks5"
+)
+
+const (
+	// max packet length
+	maxLength = 8192
+)
+
+var (
+	defaultALPN = []string{"h2", "http/1.1"}
+	crlf        = []byte{'\r', '\n'}
+
+	bufPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+	errPacketTooLarge = errors.New("packet too large")
+	errResolveAddr    = errors.New("resolve addr error")
+)
+
+type Command = byte
+
+const (
+	CommandTCP Command = 1
+	CommandUDP Command = 3
+)
+
+type Option struct {
+	Password           string
+	ALPN               []string
+	ServerName         string
+	SkipCertVerify     bool
+	ClientSessionCache tls.ClientSessionCache
+}
+
+type Trojan struct {
+	option      *Option
+	hexPassword []byte
+}
+
+// StreamConn wraps conn with the TLS layer of trojan
+func (t *Trojan) StreamConn(conn net.Conn) (net.Conn, error) {
+	alpn := defaultALPN
+	if len(t.option.ALPN) != 0 {
+		alpn = t.option.ALPN
+	}
+
+	tlsConfig := &tls.Config{
+		NextProtos:         alpn,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.option.SkipCertVerify,
+		ServerName:         t.option.ServerName,
+		ClientSessionCache: t.option.ClientSessionCache,
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// WriteHeader writes the trojan request, the server replies nothing
+func (t *Trojan) WriteHeader(w io.Writer, command Command, socks5Addr []byte) error {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	defer buf.Reset()
+
+	buf.Write(t.hexPassword)
+	buf.Write(crlf)
+
+	buf.WriteByte(command)
+	buf.Write(socks5Addr)
+	buf.Write(crlf)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// PacketConn returns a packet conn over a trojan UDP stream
+func (t *Trojan) PacketConn(conn net.Conn) *PacketConn {
+	return &PacketConn{Conn: conn}
+}
+
+// WritePacket writes payload to addr as a single trojan UDP packet
+func WritePacket(w io.Writer, socks5Addr, payload []byte) (int, error) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	defer buf.Reset()
+
+	buf.Write(socks5Addr)
+	binary.Write(buf, binary.BigEndian, uint16(len(payload)))
+	buf.Write(crlf)
+	buf.Write(payload)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(payload), nil
+}
+
+// ReadPacket reads a trojan UDP packet into payload and returns the source
+// address, a domain address is resolved
+func ReadPacket(r io.Reader, payload []byte) (*net.UDPAddr, int, error) {
+	buf := make([]byte, socks5.MaxAddrLen)
+	addr, err := socks5.ReadAddr(r, buf)
+	if err != nil {
+		return nil, 0, errors.New("read addr error")
+	}
+	// addr shares buf with the length
+	uAddr, host := addr.UDPAddr(), addr.String()
+
+	if _, err = io.ReadFull(r, buf[:4]); err != nil {
+		return nil, 0, errors.New("read length error")
+	}
+
+	length := int(binary.BigEndian.Uint16(buf[:2]))
+	if length > maxLength || len(payload) < length {
+		// drop the payload, the next packet starts right after it
+		if _, err := io.CopyN(ioutil.Discard, r, int64(length)); err != nil {
+			return nil, 0, err
+		}
+		if length > maxLength {
+			return nil, 0, errPacketTooLarge
+		}
+		return nil, 0, io.ErrShortBuffer
+	}
+
+	n, err := io.ReadFull(r, payload[:length])
+	if err != nil {
+		return nil, n, err
+	}
+
+	if uAddr == nil {
+		host, port, err := net.SplitHostPort(host)
+		if err != nil {
+			return nil, n, err
+		}
+		ip, err := resolver.ResolveIP(host)
+		if err != nil {
+			return nil, n, fmt.Errorf("%w: %s", errResolveAddr, err.Error())
+		}
+		p, _ := strconv.Atoi(port)
+		uAddr = &net.UDPAddr{IP: ip, Port: p}
+	}
+	return uAddr, n, nil
+}
+
+// PacketConn is a trojan UDP stream, every packet carries its address
+type PacketConn struct {
+	net.Conn
+	rMux sync.Mutex
+	wMux sync.Mutex
+}
+
+func (pc *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	socks5Addr := socks5.ParseAddrToSocksAddr(addr)
+	if socks5Addr == nil {
+		return 0, errors.New("invalid address")
+	}
+
+	pc.wMux.Lock()
+	defer pc.wMux.Unlock()
+	return WritePacket(pc.Conn, socks5Addr, b)
+}
+
+// WriteToSocksAddr is WriteTo with an encoded address, which may be a domain
+func (pc *PacketConn) WriteToSocksAddr(b []byte, socks5Addr []byte) (int, error) {
+	pc.wMux.Lock()
+	defer pc.wMux.Unlock()
+	return WritePacket(pc.Conn, socks5Addr, b)
+}
+
+func (pc *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	pc.rMux.Lock()
+	defer pc.rMux.Unlock()
+
+	for {
+		addr, n, err := ReadPacket(pc.Conn, b)
+		if isDropped(err) {
+			// the stream stays aligned, so only this packet is lost
+			continue
+		} else if err != nil {
+			return n, nil, err
+		}
+		return n, addr, nil
+	}
+}
+
+// isDropped reports whether ReadPacket consumed a whole packet it couldn't return
+func isDropped(err error) bool {
+	return err == io.ErrShortBuffer || err == errPacketTooLarge || errors.Is(err, errResolveAddr)
+}
+
+func hexSha224(data []byte) []byte {
+	buf := make([]byte, 56)
+	hash := sha256.New224()
+	hash.Write(data)
+	hex.Encode(buf, hash.Sum(nil))
+	return buf
+}
+
+func New(option *Option) *Trojan {
+	return &Trojan{option, hexSha224([]byte(option.Password))}
+}
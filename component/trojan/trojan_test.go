@@ -0,0 +1,65 @@
+package trojan
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Dreamacro/clash/component/resolver"
+	"github.com/Dreamacro/clash/component/socks5"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPacket_Domain(t *testing.T) {
+	resolver.DefaultHosts.Insert("trojan.example", net.ParseIP("1.2.3.4"))
+
+	stream := &bytes.Buffer{}
+	_, err := WritePacket(stream, socks5.ParseAddr("trojan.example:53"), []byte("hello"))
+	assert.Nil(t, err)
+
+	payload := make([]byte, 16)
+	addr, n, err := ReadPacket(stream, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.3.4:53", addr.String())
+	assert.Equal(t, "hello", string(payload[:n]))
+}
+
+func TestReadPacket_ShortBuffer(t *testing.T) {
+	stream := &bytes.Buffer{}
+	_, err := WritePacket(stream, socks5.ParseAddr("1.2.3.4:53"), []byte("a large packet"))
+	assert.Nil(t, err)
+	_, err = WritePacket(stream, socks5.ParseAddr("5.6.7.8:53"), []byte("small"))
+	assert.Nil(t, err)
+
+	payload := make([]byte, 8)
+	_, _, err = ReadPacket(stream, payload)
+	assert.Equal(t, io.ErrShortBuffer, err)
+
+	// the oversized packet is dropped and the next one is intact
+	addr, n, err := ReadPacket(stream, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "5.6.7.8:53", addr.String())
+	assert.Equal(t, "small", string(payload[:n]))
+}
+
+func TestPacketConn_SkipDropped(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		WritePacket(server, socks5.ParseAddr("1.2.3.4:53"), bytes.Repeat([]byte{1}, maxLength+1))
+		WritePacket(server, socks5.ParseAddr("1.2.3.4:53"), bytes.Repeat([]byte{2}, 64))
+		WritePacket(server, socks5.ParseAddr("5.6.7.8:53"), []byte("valid"))
+	}()
+
+	// the oversized packets don't end the session
+	pc := &PacketConn{Conn: client}
+	buf := make([]byte, 32)
+	n, addr, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "5.6.7.8:53", addr.String())
+	assert.Equal(t, "valid", string(buf[:n]))
+}
@@ -0,0 +1,120 @@
+package vless
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"github.com/Dreamacro/clash/component/vmess"
+)
+
+// Conn wrapper a net.Conn with vless protocol
+type Conn struct {
+	net.Conn
+	dst      *vmess.DstAddr
+	received bool
+	// bytes left of the current UDP packet
+	remain int
+}
+
+func (vc *Conn) Write(b []byte) (int, error) {
+	if !vc.dst.UDP {
+		return vc.Conn.Write(b)
+	}
+
+	// every UDP packet is prefixed with its length
+	buf := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(buf, uint16(len(b)))
+	copy(buf[2:], b)
+	if _, err := vc.Conn.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (vc *Conn) Read(b []byte) (int, error) {
+	if !vc.received {
+		if err := vc.recvResponse(); err != nil {
+			return 0, err
+		}
+		vc.received = true
+	}
+
+	if !vc.dst.UDP {
+		return vc.Conn.Read(b)
+	}
+
+	if vc.remain == 0 {
+		var length [2]byte
+		if _, err := io.ReadFull(vc.Conn, length[:]); err != nil {
+			return 0, err
+		}
+		vc.remain = int(binary.BigEndian.Uint16(length[:]))
+	}
+
+	size := vc.remain
+	if len(b) < size {
+		size = len(b)
+	}
+
+	n, err := io.ReadFull(vc.Conn, b[:size])
+	vc.remain -= n
+	return n, err
+}
+
+func (vc *Conn) sendRequest(client *Client) error {
+	buf := &bytes.Buffer{}
+
+	buf.WriteByte(Version)
+	buf.Write(client.uuid.Bytes())
+	// no addons
+	buf.WriteByte(0)
+
+	if vc.dst.UDP {
+		buf.WriteByte(CommandUDP)
+	} else {
+		buf.WriteByte(CommandTCP)
+	}
+
+	binary.Write(buf, binary.BigEndian, uint16(vc.dst.Port))
+	buf.WriteByte(vc.dst.AddrType)
+	buf.Write(vc.dst.Addr)
+
+	_, err := vc.Conn.Write(buf.Bytes())
+	return err
+}
+
+func (vc *Conn) recvResponse() error {
+	var buf [2]byte
+	if _, err := io.ReadFull(vc.Conn, buf[:]); err != nil {
+		return err
+	}
+
+	if buf[0] != Version {
+		return errors.New("unexpected response version")
+	}
+
+	// skip addons
+	if length := int64(buf[1]); length != 0 {
+		if _, err := io.CopyN(ioutil.Discard, vc.Conn, length); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newConn(conn net.Conn, client *Client, dst *vmess.DstAddr) (*Conn, error) {
+	c := &Conn{
+		Conn: conn,
+		dst:  dst,
+	}
+
+	if err := c.sendRequest(client); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,41 @@
+package vless
+
+import (
+	"net"
+
+	"github.com/Dreamacro/clash/component/vmess"
+
+	"github.com/gofrs/uuid"
+)
+
+// Version of vless
+const Version byte = 0
+
+// Command types
+const (
+	CommandTCP byte = 1
+	CommandUDP byte = 2
+)
+
+// Client is vless connection generator
+type Client struct {
+	uuid *uuid.UUID
+}
+
+// StreamConn return a Conn with net.Conn and DstAddr,
+// the address encoding of vless is the same as vmess
+func (c *Client) StreamConn(conn net.Conn, dst *vmess.DstAddr) (net.Conn, error) {
+	return newConn(conn, c, dst)
+}
+
+// NewClient return Client instance
+func NewClient(uuidStr string) (*Client, error) {
+	uid, err := uuid.FromString(uuidStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		uuid: &uid,
+	}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"hash/fnv"
@@ -34,6 +35,7 @@ type Conn struct {
 	respBodyKey []byte
 	respV       byte
 	security    byte
+	isAead      bool
 
 	received bool
 }
@@ -57,11 +59,12 @@ func (vc *Conn) Read(b []byte) (int, error) {
 func (vc *Conn) sendRequest() error {
 	timestamp := time.Now()
 
-	h := hmac.New(md5.New, vc.id.UUID.Bytes())
-	binary.Write(h, binary.BigEndian, uint64(timestamp.Unix()))
-	_, err := vc.Conn.Write(h.Sum(nil))
-	if err != nil {
-		return err
+	if !vc.isAead {
+		h := hmac.New(md5.New, vc.id.UUID.Bytes())
+		binary.Write(h, binary.BigEndian, uint64(timestamp.Unix()))
+		if _, err := vc.Conn.Write(h.Sum(nil)); err != nil {
+			return err
+		}
 	}
 
 	buf := &bytes.Buffer{}
@@ -99,6 +102,11 @@ func (vc *Conn) sendRequest() error {
 	fnv1a.Write(buf.Bytes())
 	buf.Write(fnv1a.Sum(nil))
 
+	if vc.isAead {
+		_, err := vc.Conn.Write(sealAEADHeader(vc.id.CmdKey, buf.Bytes(), timestamp))
+		return err
+	}
+
 	block, err := aes.NewCipher(vc.id.CmdKey)
 	if err != nil {
 		return err
@@ -111,18 +119,30 @@ func (vc *Conn) sendRequest() error {
 }
 
 func (vc *Conn) recvResponse() error {
-	block, err := aes.NewCipher(vc.respBodyKey[:])
-	if err != nil {
-		return err
-	}
-
-	stream := cipher.NewCFBDecrypter(block, vc.respBodyIV[:])
-	buf := make([]byte, 4)
-	_, err = io.ReadFull(vc.Conn, buf)
-	if err != nil {
-		return err
+	var buf []byte
+	if vc.isAead {
+		header, err := openAEADResponseHeader(vc.Conn, vc.respBodyKey, vc.respBodyIV)
+		if err != nil {
+			return err
+		}
+
+		if len(header) < 4 {
+			return errors.New("unexpected response header")
+		}
+		buf = header
+	} else {
+		block, err := aes.NewCipher(vc.respBodyKey[:])
+		if err != nil {
+			return err
+		}
+
+		stream := cipher.NewCFBDecrypter(block, vc.respBodyIV[:])
+		buf = make([]byte, 4)
+		if _, err = io.ReadFull(vc.Conn, buf); err != nil {
+			return err
+		}
+		stream.XORKeyStream(buf, buf)
 	}
-	stream.XORKeyStream(buf, buf)
 
 	if buf[0] != vc.respV {
 		return errors.New("unexpected response header")
@@ -146,19 +166,9 @@ func hashTimestamp(t time.Time) []byte {
 	return md5hash.Sum(nil)
 }
 
-// newConn return a Conn instance
-func newConn(conn net.Conn, id *ID, dst *DstAddr, security Security) (*Conn, error) {
-	randBytes := make([]byte, 33)
-	rand.Read(randBytes)
-	reqBodyIV := make([]byte, 16)
-	reqBodyKey := make([]byte, 16)
-	copy(reqBodyIV[:], randBytes[:16])
-	copy(reqBodyKey[:], randBytes[16:32])
-	respV := randBytes[32]
-
-	respBodyKey := md5.Sum(reqBodyKey[:])
-	respBodyIV := md5.Sum(reqBodyIV[:])
-
+// newBodyStream returns the body reader and writer of security,
+// the writer is keyed by wKey/wIV and the reader by rKey/rIV
+func newBodyStream(conn net.Conn, security Security, wKey, wIV, rKey, rIV []byte) (io.Reader, io.Writer) {
 	var writer io.Writer
 	var reader io.Reader
 	switch security {
@@ -166,30 +176,57 @@ func newConn(conn net.Conn, id *ID, dst *DstAddr, security Security) (*Conn, err
 		reader = newChunkReader(conn)
 		writer = newChunkWriter(conn)
 	case SecurityAES128GCM:
-		block, _ := aes.NewCipher(reqBodyKey[:])
+		block, _ := aes.NewCipher(wKey)
 		aead, _ := cipher.NewGCM(block)
-		writer = newAEADWriter(conn, aead, reqBodyIV[:])
+		writer = newAEADWriter(conn, aead, wIV)
 
-		block, _ = aes.NewCipher(respBodyKey[:])
+		block, _ = aes.NewCipher(rKey)
 		aead, _ = cipher.NewGCM(block)
-		reader = newAEADReader(conn, aead, respBodyIV[:])
+		reader = newAEADReader(conn, aead, rIV)
 	case SecurityCHACHA20POLY1305:
 		key := make([]byte, 32)
-		t := md5.Sum(reqBodyKey[:])
+		t := md5.Sum(wKey)
 		copy(key, t[:])
 		t = md5.Sum(key[:16])
 		copy(key[16:], t[:])
 		aead, _ := chacha20poly1305.New(key)
-		writer = newAEADWriter(conn, aead, reqBodyIV[:])
+		writer = newAEADWriter(conn, aead, wIV)
 
-		t = md5.Sum(respBodyKey[:])
+		key = make([]byte, 32)
+		t = md5.Sum(rKey)
 		copy(key, t[:])
 		t = md5.Sum(key[:16])
 		copy(key[16:], t[:])
 		aead, _ = chacha20poly1305.New(key)
-		reader = newAEADReader(conn, aead, respBodyIV[:])
+		reader = newAEADReader(conn, aead, rIV)
+	}
+
+	return reader, writer
+}
+
+// newConn return a Conn instance
+func newConn(conn net.Conn, id *ID, dst *DstAddr, security Security, isAead bool) (*Conn, error) {
+	randBytes := make([]byte, 33)
+	rand.Read(randBytes)
+	reqBodyIV := make([]byte, 16)
+	reqBodyKey := make([]byte, 16)
+	copy(reqBodyIV[:], randBytes[:16])
+	copy(reqBodyKey[:], randBytes[16:32])
+	respV := randBytes[32]
+
+	var respBodyKey, respBodyIV []byte
+	if isAead {
+		key := sha256.Sum256(reqBodyKey)
+		iv := sha256.Sum256(reqBodyIV)
+		respBodyKey, respBodyIV = key[:16], iv[:16]
+	} else {
+		key := md5.Sum(reqBodyKey)
+		iv := md5.Sum(reqBodyIV)
+		respBodyKey, respBodyIV = key[:], iv[:]
 	}
 
+	reader, writer := newBodyStream(conn, security, reqBodyKey, reqBodyIV, respBodyKey, respBodyIV)
+
 	c := &Conn{
 		Conn:        conn,
 		id:          id,
@@ -197,11 +234,12 @@ func newConn(conn net.Conn, id *ID, dst *DstAddr, security Security) (*Conn, err
 		reqBodyIV:   reqBodyIV,
 		reqBodyKey:  reqBodyKey,
 		respV:       respV,
-		respBodyIV:  respBodyIV[:],
-		respBodyKey: respBodyKey[:],
+		respBodyIV:  respBodyIV,
+		respBodyKey: respBodyKey,
 		reader:      reader,
 		writer:      writer,
 		security:    security,
+		isAead:      isAead,
 	}
 	if err := c.sendRequest(); err != nil {
 		return nil, err
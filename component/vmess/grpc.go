@@ -0,0 +1,142 @@
+package vmess
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// GrpcConfig of the grpc ("gun") transport
+type GrpcConfig struct {
+	ServiceName string
+}
+
+var errInvalidGrpcMessage = errors.New("invalid grpc message")
+
+// grpcConn carries the stream as the data field of a protobuf message
+// (`message Hunk { bytes data = 1; }`) in grpc length-prefixed frames
+type grpcConn struct {
+	net.Conn
+	remain int
+	skip   int
+}
+
+func newGrpcConn(conn net.Conn) net.Conn {
+	return &grpcConn{Conn: conn}
+}
+
+func (gc *grpcConn) Read(b []byte) (int, error) {
+	for gc.remain == 0 {
+		if gc.skip > 0 {
+			if _, err := io.CopyN(ioutil.Discard, gc.Conn, int64(gc.skip)); err != nil {
+				return 0, err
+			}
+			gc.skip = 0
+		}
+
+		if err := gc.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(b) > gc.remain {
+		b = b[:gc.remain]
+	}
+
+	n, err := gc.Conn.Read(b)
+	gc.remain -= n
+	return n, err
+}
+
+// readHeader parses the grpc frame header and the protobuf field header of
+// the next message
+func (gc *grpcConn) readHeader() error {
+	// compressed flag | message length
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(gc.Conn, buf); err != nil {
+		return err
+	}
+	if buf[0] != 0 {
+		return errInvalidGrpcMessage
+	}
+
+	length := int(binary.BigEndian.Uint32(buf[1:]))
+	if length == 0 {
+		return nil
+	}
+
+	if _, err := io.ReadFull(gc.Conn, buf[:1]); err != nil {
+		return err
+	}
+	if buf[0] != 0x0a {
+		return errInvalidGrpcMessage
+	}
+
+	dataLen, n, err := readUvarint(gc.Conn)
+	if err != nil {
+		return err
+	}
+
+	skip := length - 1 - n - int(dataLen)
+	if skip < 0 {
+		return errInvalidGrpcMessage
+	}
+
+	gc.remain = int(dataLen)
+	gc.skip = skip
+	return nil
+}
+
+func (gc *grpcConn) Write(b []byte) (int, error) {
+	varint := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(varint, uint64(len(b)))
+
+	buf := make([]byte, 5+1+n+len(b))
+	binary.BigEndian.PutUint32(buf[1:5], uint32(1+n+len(b)))
+	buf[5] = 0x0a
+	copy(buf[6:], varint[:n])
+	copy(buf[6+n:], b)
+
+	if _, err := gc.Conn.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// readUvarint reads a varint byte by byte, without reading ahead of it
+func readUvarint(r io.Reader) (uint64, int, error) {
+	var x uint64
+	var s uint
+	b := make([]byte, 1)
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, i, err
+		}
+		if b[0] < 0x80 {
+			return x | uint64(b[0])<<s, i + 1, nil
+		}
+		x |= uint64(b[0]&0x7f) << s
+		s += 7
+	}
+	return 0, binary.MaxVarintLen64, errInvalidGrpcMessage
+}
+
+// newGrpcRequest builds the request of the gun transport
+func newGrpcRequest(c *GrpcConfig, scheme, host string) *http.Request {
+	return &http.Request{
+		Method:     http.MethodPost,
+		Host:       host,
+		URL:        &url.URL{Scheme: scheme, Host: host, Path: "/" + c.ServiceName + "/Tun"},
+		Proto:      "HTTP/2",
+		ProtoMajor: 2,
+		Header: http.Header{
+			"Content-Type": []string{"application/grpc"},
+			"User-Agent":   []string{"grpc-go/1.36.0"},
+			"Te":           []string{"trailers"},
+		},
+	}
+}
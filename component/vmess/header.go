@@ -0,0 +1,124 @@
+package vmess
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// salts of the AEAD header KDF
+const (
+	kdfSaltConstAuthIDEncryptionKey             = "AES Auth ID Encryption"
+	kdfSaltConstAEADRespHeaderLenKey            = "AEAD Resp Header Len Key"
+	kdfSaltConstAEADRespHeaderLenIV             = "AEAD Resp Header Len IV"
+	kdfSaltConstAEADRespHeaderPayloadKey        = "AEAD Resp Header Key"
+	kdfSaltConstAEADRespHeaderPayloadIV         = "AEAD Resp Header IV"
+	kdfSaltConstVMessAEADKDF                    = "VMess AEAD KDF"
+	kdfSaltConstVMessHeaderPayloadAEADKey       = "VMess Header AEAD Key"
+	kdfSaltConstVMessHeaderPayloadAEADIV        = "VMess Header AEAD Nonce"
+	kdfSaltConstVMessHeaderPayloadLengthAEADKey = "VMess Header AEAD Key_Length"
+	kdfSaltConstVMessHeaderPayloadLengthAEADIV  = "VMess Header AEAD Nonce_Length"
+)
+
+// kdf is the nested HMAC-SHA256 key derivation of the AEAD header,
+// every element of path wraps the previous HMAC as its hash function
+func kdf(key []byte, path ...string) []byte {
+	creator := func() hash.Hash {
+		return hmac.New(sha256.New, []byte(kdfSaltConstVMessAEADKDF))
+	}
+	for _, p := range path {
+		parent, value := creator, []byte(p)
+		creator = func() hash.Hash {
+			return hmac.New(parent, value)
+		}
+	}
+
+	h := creator()
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+func kdf16(key []byte, path ...string) []byte {
+	return kdf(key, path...)[:16]
+}
+
+func newGCM(key []byte) cipher.AEAD {
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+// createAuthID encrypts the timestamp, 4 random bytes and their crc32 with cmdKey
+func createAuthID(cmdKey []byte, timestamp int64, random []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, timestamp)
+	buf.Write(random)
+	binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+
+	block, _ := aes.NewCipher(kdf16(cmdKey, kdfSaltConstAuthIDEncryptionKey))
+	authID := make([]byte, 16)
+	block.Encrypt(authID, buf.Bytes())
+	return authID
+}
+
+// sealAEADHeader encodes the request header as
+// AuthID | sealed length | connection nonce | sealed header
+func sealAEADHeader(cmdKey []byte, header []byte, t time.Time) []byte {
+	random := make([]byte, 4+8)
+	rand.Read(random)
+	return sealHeader(cmdKey, header, createAuthID(cmdKey, t.Unix(), random[:4]), random[4:])
+}
+
+func sealHeader(cmdKey []byte, header []byte, authID []byte, nonce []byte) []byte {
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(header)))
+
+	lengthKey := kdf16(cmdKey, kdfSaltConstVMessHeaderPayloadLengthAEADKey, string(authID), string(nonce))
+	lengthIV := kdf(cmdKey, kdfSaltConstVMessHeaderPayloadLengthAEADIV, string(authID), string(nonce))[:12]
+	sealedLength := newGCM(lengthKey).Seal(nil, lengthIV, length, authID)
+
+	headerKey := kdf16(cmdKey, kdfSaltConstVMessHeaderPayloadAEADKey, string(authID), string(nonce))
+	headerIV := kdf(cmdKey, kdfSaltConstVMessHeaderPayloadAEADIV, string(authID), string(nonce))[:12]
+	sealedHeader := newGCM(headerKey).Seal(nil, headerIV, header, authID)
+
+	return bytes.Join([][]byte{authID, sealedLength, nonce, sealedHeader}, nil)
+}
+
+// openAEADResponseHeader reads the sealed length and the sealed response header
+func openAEADResponseHeader(r io.Reader, respBodyKey, respBodyIV []byte) ([]byte, error) {
+	lengthKey := kdf16(respBodyKey, kdfSaltConstAEADRespHeaderLenKey)
+	lengthIV := kdf(respBodyIV, kdfSaltConstAEADRespHeaderLenIV)[:12]
+
+	buf := make([]byte, 2+16)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	length, err := newGCM(lengthKey).Open(nil, lengthIV, buf, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt response header length")
+	}
+
+	headerKey := kdf16(respBodyKey, kdfSaltConstAEADRespHeaderPayloadKey)
+	headerIV := kdf(respBodyIV, kdfSaltConstAEADRespHeaderPayloadIV)[:12]
+
+	buf = make([]byte, int(binary.BigEndian.Uint16(length))+16)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	header, err := newGCM(headerKey).Open(nil, headerIV, buf, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt response header")
+	}
+	return header, nil
+}
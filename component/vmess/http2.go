@@ -0,0 +1,219 @@
+package vmess
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// DialFunc dials the underlying connection of a multiplexed transport
+type DialFunc func(ctx context.Context) (net.Conn, error)
+
+// HTTP2Config of the h2 transport
+type HTTP2Config struct {
+	Hosts []string
+	Path  string
+}
+
+// http2Mux runs every stream of a client over one shared http2 connection
+type http2Mux struct {
+	tlsConfig *tls.Config
+	transport *http2.Transport
+
+	mux        sync.Mutex
+	cc         *http2.ClientConn
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func newHTTP2Mux(tlsConfig *tls.Config) *http2Mux {
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.NextProtos = []string{"h2"}
+	}
+
+	return &http2Mux{
+		tlsConfig: tlsConfig,
+		transport: &http2.Transport{},
+	}
+}
+
+// clientConn returns the shared connection, dial is only called when there
+// is no connection able to take a new stream
+func (m *http2Mux) clientConn(ctx context.Context, dial DialFunc) (*http2.ClientConn, net.Addr, net.Addr, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.cc != nil && m.cc.CanTakeNewRequest() {
+		return m.cc, m.localAddr, m.remoteAddr, nil
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if m.tlsConfig != nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		tlsConn := tls.Client(conn, m.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, nil, nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	cc, err := m.transport.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	m.cc = cc
+	m.localAddr = conn.LocalAddr()
+	m.remoteAddr = conn.RemoteAddr()
+	return cc, m.localAddr, m.remoteAddr, nil
+}
+
+// Close closes the shared connection, the next stream dials a new one
+func (m *http2Mux) Close() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.cc == nil {
+		return nil
+	}
+	err := m.cc.Close()
+	m.cc = nil
+	return err
+}
+
+// open starts a bidirectional stream with req, the request body is fed by
+// the returned conn
+func (m *http2Mux) open(ctx context.Context, dial DialFunc, req *http.Request) (*http2Conn, error) {
+	cc, localAddr, remoteAddr, err := m.clientConn(ctx, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req = req.WithContext(streamCtx)
+	req.Body = reader
+	req.ContentLength = -1
+
+	hc := &http2Conn{
+		writer:     writer,
+		cancel:     cancel,
+		ready:      make(chan struct{}),
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+	}
+
+	// RoundTrip returns only after the response header, while the server
+	// may wait for the request body first
+	go func() {
+		defer close(hc.ready)
+
+		resp, err := cc.RoundTrip(req)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+
+		if err != nil {
+			hc.err = err
+			reader.CloseWithError(err)
+			return
+		}
+		hc.body = resp.Body
+	}()
+
+	return hc, nil
+}
+
+// http2Conn is a net.Conn over one http2 stream
+type http2Conn struct {
+	writer     *io.PipeWriter
+	body       io.ReadCloser
+	cancel     context.CancelFunc
+	ready      chan struct{}
+	err        error
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (hc *http2Conn) Read(b []byte) (int, error) {
+	<-hc.ready
+	if hc.err != nil {
+		return 0, hc.err
+	}
+	return hc.body.Read(b)
+}
+
+func (hc *http2Conn) Write(b []byte) (int, error) {
+	return hc.writer.Write(b)
+}
+
+func (hc *http2Conn) Close() error {
+	hc.writer.Close()
+	hc.cancel()
+	return nil
+}
+
+func (hc *http2Conn) LocalAddr() net.Addr {
+	return hc.localAddr
+}
+
+func (hc *http2Conn) RemoteAddr() net.Addr {
+	return hc.remoteAddr
+}
+
+// deadlines are not supported by http2 streams
+
+func (hc *http2Conn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (hc *http2Conn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (hc *http2Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// newHTTP2Request builds the request of the h2 transport, the host is picked
+// randomly from the config
+func newHTTP2Request(c *HTTP2Config, scheme, defaultHost string) *http.Request {
+	host := defaultHost
+	if len(c.Hosts) > 0 {
+		host = c.Hosts[rand.Intn(len(c.Hosts))]
+	}
+
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return &http.Request{
+		Method:     http.MethodPut,
+		Host:       host,
+		URL:        &url.URL{Scheme: scheme, Host: host, Path: path},
+		Proto:      "HTTP/2",
+		ProtoMajor: 2,
+		Header:     http.Header{"Accept-Encoding": []string{"identity"}},
+	}
+}
@@ -0,0 +1,156 @@
+package vmess
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+// handlerConn turns a http2 request into the stream of a vmess server
+type handlerConn struct {
+	net.Conn
+	r io.Reader
+	w http.ResponseWriter
+}
+
+func (hc *handlerConn) Read(b []byte) (int, error) {
+	return hc.r.Read(b)
+}
+
+func (hc *handlerConn) Write(b []byte) (int, error) {
+	n, err := hc.w.Write(b)
+	hc.w.(http.Flusher).Flush()
+	return n, err
+}
+
+func (hc *handlerConn) Close() error {
+	return nil
+}
+
+// serveHTTP2 serves h2c on a random local port, counting the accepted connections
+func serveHTTP2(handler http.HandlerFunc, accepted *int32) net.Listener {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := &http2.Server{}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(accepted, 1)
+			go server.ServeConn(c, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	return l
+}
+
+func testStreams(t *testing.T, l net.Listener, accepted *int32, config Config) {
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	config.UUID = testUUID
+	config.Security = "aes-128-gcm"
+	config.HostName = host
+	config.Port = port
+
+	client, err := NewClient(config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, client.Multiplexed())
+
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}
+
+	for i := 0; i < 4; i++ {
+		// the stream after Close needs a new connection
+		if i == 3 {
+			assert.Nil(t, client.Close())
+		}
+
+		dst := &DstAddr{AddrType: AtypIPv4, Addr: []byte{1, 1, 1, 1}, Port: 80}
+		conn, err := client.NewStream(context.Background(), dial, dst)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		msg := []byte("hello stream " + strconv.Itoa(i))
+		go conn.Write(msg)
+
+		buf := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, buf)
+		assert.Nil(t, err)
+		assert.Equal(t, msg, buf)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(accepted))
+}
+
+func TestVmess_HTTP2(t *testing.T) {
+	var accepted int32
+	l := serveHTTP2(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Host != "example.com" || r.URL.Path != "/vmess" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		serveVmess(&handlerConn{r: r.Body, w: w}, 0, make(chan *testRequest, 1))
+	}, &accepted)
+	defer l.Close()
+
+	testStreams(t, l, &accepted, Config{
+		NetWork:    "h2",
+		HTTP2Hosts: []string{"example.com"},
+		HTTP2Path:  "/vmess",
+	})
+}
+
+func TestVmess_Grpc(t *testing.T) {
+	var accepted int32
+	l := serveHTTP2(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/example/Tun" || r.Header.Get("Content-Type") != "application/grpc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		serveVmess(newGrpcConn(&handlerConn{r: r.Body, w: w}), 0, make(chan *testRequest, 1))
+	}, &accepted)
+	defer l.Close()
+
+	testStreams(t, l, &accepted, Config{
+		NetWork:         "grpc",
+		GrpcServiceName: "example",
+	})
+}
+
+func TestVmess_HTTP2Status(t *testing.T) {
+	var accepted int32
+	l := serveHTTP2(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}, &accepted)
+	defer l.Close()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	client, _ := NewClient(Config{UUID: testUUID, Security: "none", HostName: host, Port: port, NetWork: "h2"})
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}
+
+	// the request header is written before the response, so the error shows up on read
+	conn, err := client.NewStream(context.Background(), dial, &DstAddr{AddrType: AtypIPv4, Addr: []byte{1, 1, 1, 1}, Port: 80})
+	if err == nil {
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	assert.NotNil(t, err)
+}
@@ -1,7 +1,9 @@
 package vmess
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -71,8 +73,16 @@ type Client struct {
 	security  Security
 	tls       bool
 	host      string
+	hostName  string
 	wsConfig  *WebsocketConfig
 	tlsConfig *tls.Config
+	// alterId 0 uses the AEAD header
+	isAead bool
+
+	// h2 and grpc streams share one connection
+	mux        *http2Mux
+	h2Config   *HTTP2Config
+	grpcConfig *GrpcConfig
 }
 
 // Config of vmess
@@ -86,6 +96,9 @@ type Config struct {
 	NetWork          string
 	WebSocketPath    string
 	WebSocketHeaders map[string]string
+	HTTP2Hosts       []string
+	HTTP2Path        string
+	GrpcServiceName  string
 	SkipCertVerify   bool
 	SessionCache     tls.ClientSessionCache
 }
@@ -102,7 +115,59 @@ func (c *Client) New(conn net.Conn, dst *DstAddr) (net.Conn, error) {
 	} else if c.tls {
 		conn = tls.Client(conn, c.tlsConfig)
 	}
-	return newConn(conn, c.user[r], dst, c.security)
+	return newConn(conn, c.user[r], dst, c.security, c.isAead)
+}
+
+// Multiplexed reports whether the connections of the client are streams of
+// a shared connection, which are opened by NewStream instead of New
+func (c *Client) Multiplexed() bool {
+	return c.mux != nil
+}
+
+// NewStream return a Conn over a new stream of the shared connection,
+// dial is only called when a new underlying connection is needed
+func (c *Client) NewStream(ctx context.Context, dial DialFunc, dst *DstAddr) (net.Conn, error) {
+	if c.mux == nil {
+		return nil, errors.New("network is not multiplexed")
+	}
+
+	scheme := "http"
+	if c.tls {
+		scheme = "https"
+	}
+
+	var req *http.Request
+	if c.grpcConfig != nil {
+		req = newGrpcRequest(c.grpcConfig, scheme, c.hostName)
+	} else {
+		req = newHTTP2Request(c.h2Config, scheme, c.hostName)
+	}
+
+	hc, err := c.mux.open(ctx, dial, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn = hc
+	if c.grpcConfig != nil {
+		conn = newGrpcConn(hc)
+	}
+
+	r := rand.Intn(len(c.user))
+	vc, err := newConn(conn, c.user[r], dst, c.security, c.isAead)
+	if err != nil {
+		hc.Close()
+		return nil, err
+	}
+	return vc, nil
+}
+
+// Close closes the shared connection of h2 and grpc
+func (c *Client) Close() error {
+	if c.mux == nil {
+		return nil
+	}
+	return c.mux.Close()
 }
 
 // NewClient return Client instance
@@ -129,7 +194,9 @@ func NewClient(config Config) (*Client, error) {
 		return nil, fmt.Errorf("Unknown security type: %s", config.Security)
 	}
 
-	if config.NetWork != "" && config.NetWork != "ws" {
+	switch config.NetWork {
+	case "", "ws", "h2", "grpc":
+	default:
 		return nil, fmt.Errorf("Unknown network type: %s", config.NetWork)
 	}
 
@@ -166,15 +233,33 @@ func NewClient(config Config) (*Client, error) {
 		}
 	}
 
-	return &Client{
+	client := &Client{
 		user:      newAlterIDs(newID(&uid), config.AlterID),
 		uuid:      &uid,
 		security:  security,
 		tls:       config.TLS,
 		host:      host,
+		hostName:  config.HostName,
 		wsConfig:  wsConfig,
 		tlsConfig: tlsConfig,
-	}, nil
+		isAead:    config.AlterID == 0,
+	}
+
+	switch config.NetWork {
+	case "h2":
+		client.mux = newHTTP2Mux(tlsConfig)
+		client.h2Config = &HTTP2Config{
+			Hosts: config.HTTP2Hosts,
+			Path:  config.HTTP2Path,
+		}
+	case "grpc":
+		client.mux = newHTTP2Mux(tlsConfig)
+		client.grpcConfig = &GrpcConfig{
+			ServiceName: config.GrpcServiceName,
+		}
+	}
+
+	return client, nil
 }
 
 func getClientSessionCache() tls.ClientSessionCache {
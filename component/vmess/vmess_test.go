@@ -0,0 +1,335 @@
+package vmess
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const testUUID = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+type testRequest struct {
+	key      []byte
+	iv       []byte
+	respV    byte
+	security Security
+	command  byte
+	port     uint16
+	addrType byte
+	addr     []byte
+}
+
+// readRequest parses the plain request header from r and checks its hash
+func readRequest(r io.Reader) (*testRequest, error) {
+	buf := &bytes.Buffer{}
+	read := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		return b, nil
+	}
+
+	// Ver IV Key V Opt P|Sec Reserve Cmd Port AddrType
+	head, err := read(1 + 16 + 16 + 1 + 1 + 1 + 1 + 1 + 2 + 1)
+	if err != nil {
+		return nil, err
+	}
+	if head[0] != Version {
+		return nil, errors.New("unexpected version")
+	}
+
+	req := &testRequest{
+		iv:       head[1:17],
+		key:      head[17:33],
+		respV:    head[33],
+		security: head[35] & 0x0f,
+		command:  head[37],
+		port:     binary.BigEndian.Uint16(head[38:40]),
+		addrType: head[40],
+	}
+
+	var addrLen int
+	switch req.addrType {
+	case AtypIPv4:
+		addrLen = net.IPv4len
+	case AtypIPv6:
+		addrLen = net.IPv6len
+	case AtypDomainName:
+		l, err := read(1)
+		if err != nil {
+			return nil, err
+		}
+		req.addr = l
+		addrLen = int(l[0])
+	}
+
+	addr, err := read(addrLen)
+	if err != nil {
+		return nil, err
+	}
+	req.addr = append(req.addr, addr...)
+
+	if _, err := read(int(head[35] >> 4)); err != nil {
+		return nil, err
+	}
+
+	fnv1a := fnv.New32a()
+	fnv1a.Write(buf.Bytes())
+	sum := make([]byte, 4)
+	if _, err := io.ReadFull(r, sum); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sum, fnv1a.Sum(nil)) {
+		return nil, errors.New("header hash mismatch")
+	}
+
+	return req, nil
+}
+
+// readAEADRequest opens the AEAD request header sent with cmdKey
+func readAEADRequest(conn io.Reader, cmdKey []byte) (*testRequest, error) {
+	authID := make([]byte, 16)
+	if _, err := io.ReadFull(conn, authID); err != nil {
+		return nil, err
+	}
+
+	block, _ := aes.NewCipher(kdf16(cmdKey, kdfSaltConstAuthIDEncryptionKey))
+	plain := make([]byte, 16)
+	block.Decrypt(plain, authID)
+	if crc32.ChecksumIEEE(plain[:12]) != binary.BigEndian.Uint32(plain[12:]) {
+		return nil, errors.New("invalid auth id")
+	}
+	if delta := time.Now().Unix() - int64(binary.BigEndian.Uint64(plain[:8])); delta > 120 || delta < -120 {
+		return nil, errors.New("auth id expired")
+	}
+
+	buf := make([]byte, 18+8)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	sealedLength, nonce := buf[:18], buf[18:]
+
+	lengthKey := kdf16(cmdKey, kdfSaltConstVMessHeaderPayloadLengthAEADKey, string(authID), string(nonce))
+	lengthIV := kdf(cmdKey, kdfSaltConstVMessHeaderPayloadLengthAEADIV, string(authID), string(nonce))[:12]
+	length, err := newGCM(lengthKey).Open(nil, lengthIV, sealedLength, authID)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedHeader := make([]byte, int(binary.BigEndian.Uint16(length))+16)
+	if _, err := io.ReadFull(conn, sealedHeader); err != nil {
+		return nil, err
+	}
+
+	headerKey := kdf16(cmdKey, kdfSaltConstVMessHeaderPayloadAEADKey, string(authID), string(nonce))
+	headerIV := kdf(cmdKey, kdfSaltConstVMessHeaderPayloadAEADIV, string(authID), string(nonce))[:12]
+	header, err := newGCM(headerKey).Open(nil, headerIV, sealedHeader, authID)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRequest(bytes.NewReader(header))
+}
+
+// readLegacyRequest checks the timestamp HMAC against ids and decrypts the header
+func readLegacyRequest(conn io.Reader, ids []*ID) (*testRequest, error) {
+	auth := make([]byte, 16)
+	if _, err := io.ReadFull(conn, auth); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for delta := -2; delta <= 2; delta++ {
+		timestamp := now.Add(time.Duration(delta) * time.Second)
+		for _, id := range ids {
+			h := hmac.New(md5.New, id.UUID.Bytes())
+			binary.Write(h, binary.BigEndian, uint64(timestamp.Unix()))
+			if !hmac.Equal(h.Sum(nil), auth) {
+				continue
+			}
+
+			block, _ := aes.NewCipher(id.CmdKey)
+			stream := cipher.NewCFBDecrypter(block, hashTimestamp(timestamp))
+			return readRequest(&cipher.StreamReader{S: stream, R: conn})
+		}
+	}
+
+	return nil, errors.New("invalid user")
+}
+
+// serveVmess is a vmess server echoing the body back
+func serveVmess(conn net.Conn, alterID uint16, requests chan<- *testRequest) {
+	defer conn.Close()
+
+	uid := uuid.FromStringOrNil(testUUID)
+	id := newID(&uid)
+	isAead := alterID == 0
+
+	var req *testRequest
+	var err error
+	if isAead {
+		req, err = readAEADRequest(conn, id.CmdKey)
+	} else {
+		req, err = readLegacyRequest(conn, newAlterIDs(id, alterID))
+	}
+	if err != nil {
+		return
+	}
+	requests <- req
+
+	var respBodyKey, respBodyIV []byte
+	if isAead {
+		key := sha256.Sum256(req.key)
+		iv := sha256.Sum256(req.iv)
+		respBodyKey, respBodyIV = key[:16], iv[:16]
+
+		header := []byte{req.respV, 0, 0, 0}
+		length := []byte{0, byte(len(header))}
+		lengthKey := kdf16(respBodyKey, kdfSaltConstAEADRespHeaderLenKey)
+		lengthIV := kdf(respBodyIV, kdfSaltConstAEADRespHeaderLenIV)[:12]
+		headerKey := kdf16(respBodyKey, kdfSaltConstAEADRespHeaderPayloadKey)
+		headerIV := kdf(respBodyIV, kdfSaltConstAEADRespHeaderPayloadIV)[:12]
+
+		conn.Write(newGCM(lengthKey).Seal(nil, lengthIV, length, nil))
+		conn.Write(newGCM(headerKey).Seal(nil, headerIV, header, nil))
+	} else {
+		key := md5.Sum(req.key)
+		iv := md5.Sum(req.iv)
+		respBodyKey, respBodyIV = key[:], iv[:]
+
+		block, _ := aes.NewCipher(respBodyKey)
+		header := []byte{req.respV, 0, 0, 0}
+		cipher.NewCFBEncrypter(block, respBodyIV).XORKeyStream(header, header)
+		conn.Write(header)
+	}
+
+	reader, writer := newBodyStream(conn, req.security, respBodyKey, respBodyIV, req.key, req.iv)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buf)
+		if err != nil {
+			return
+		}
+		if _, err := writer.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+func testRoundTrip(t *testing.T, alterID uint16, security string, udp bool) {
+	client, err := NewClient(Config{
+		UUID:     testUUID,
+		AlterID:  alterID,
+		Security: security,
+		HostName: "example.com",
+		Port:     "443",
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, alterID == 0, client.isAead)
+
+	requests := make(chan *testRequest, 1)
+	clientConn, serverConn := net.Pipe()
+	go serveVmess(serverConn, alterID, requests)
+
+	dst := &DstAddr{
+		UDP:      udp,
+		AddrType: AtypDomainName,
+		Addr:     append([]byte{byte(len("example.org"))}, "example.org"...),
+		Port:     8443,
+	}
+
+	done := make(chan struct{})
+	var conn net.Conn
+	go func() {
+		conn, err = client.New(clientConn, dst)
+		close(done)
+	}()
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, CipherMapping[security], req.security)
+		assert.Equal(t, uint16(8443), req.port)
+		assert.Equal(t, dst.Addr, req.addr)
+		if udp {
+			assert.Equal(t, CommandUDP, req.command)
+		} else {
+			assert.Equal(t, CommandTCP, req.command)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("server didn't accept the request")
+	}
+
+	<-done
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	for _, msg := range [][]byte{[]byte("hello vmess"), bytes.Repeat([]byte{1}, 20*1024)} {
+		go conn.Write(msg)
+
+		buf := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, buf)
+		assert.Nil(t, err)
+		assert.Equal(t, msg, buf)
+	}
+}
+
+func TestVmess_AEAD(t *testing.T) {
+	for _, security := range []string{"none", "aes-128-gcm", "chacha20-poly1305"} {
+		testRoundTrip(t, 0, security, false)
+	}
+	testRoundTrip(t, 0, "aes-128-gcm", true)
+}
+
+func TestVmess_Legacy(t *testing.T) {
+	for _, security := range []string{"none", "aes-128-gcm", "chacha20-poly1305"} {
+		testRoundTrip(t, 4, security, false)
+	}
+}
+
+func TestVmess_KDF(t *testing.T) {
+	// every path element changes the derived key
+	key := []byte("key")
+	assert.Equal(t, 32, len(kdf(key)))
+	assert.NotEqual(t, kdf(key), kdf(key, "a"))
+	assert.NotEqual(t, kdf(key, "a"), kdf(key, "a", "b"))
+	assert.NotEqual(t, kdf(key, "a", "b"), kdf(key, "b", "a"))
+	assert.Equal(t, kdf(key, "a", "b"), kdf(key, "a", "b"))
+}
+
+// the vectors are computed by an independent implementation of the v2ray
+// AEAD header, with HMAC, AES and AES-GCM from python and openssl
+func TestVmess_KDFVectors(t *testing.T) {
+	cmdKey := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+
+	assert.Equal(t, "ebdb909829820c287b7d7601fe00d5b093f4485a027311c2205e9007d5fc9c16", hex.EncodeToString(kdf(cmdKey)))
+	assert.Equal(t, "9fa4289c41650861a45b34aeab3879fe4785dce57ab3f68cfb0cc60fca69460a", hex.EncodeToString(kdf(cmdKey, kdfSaltConstAuthIDEncryptionKey)))
+	assert.Equal(t, "707f4b56e2ea96946abb90b47a3c089a003da786cf64cf6b1016325d46c1c885", hex.EncodeToString(kdf(cmdKey, kdfSaltConstVMessHeaderPayloadAEADKey, "a", "b")))
+
+	authID := createAuthID(cmdKey, 1600000000, []byte{0x01, 0x02, 0x03, 0x04})
+	assert.Equal(t, "c1e3f59819859151fe5f5af39af3c6c6", hex.EncodeToString(authID))
+
+	nonce := []byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
+	sealed := sealHeader(cmdKey, []byte("vmess header"), authID, nonce)
+	assert.Equal(t, "c1e3f59819859151fe5f5af39af3c6c668fe0400e794cc7f66703d8b40acee91a2141011121314151617392ef8912398c1ca9d33ad0fea233204eb042965e6a21b809c9ee763", hex.EncodeToString(sealed))
+}
@@ -0,0 +1,600 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/netstack/tcpip"
+	"github.com/google/netstack/tcpip/adapters/gonet"
+	"github.com/google/netstack/tcpip/buffer"
+	"github.com/google/netstack/tcpip/link/channel"
+	"github.com/google/netstack/tcpip/network/ipv4"
+	"github.com/google/netstack/tcpip/network/ipv6"
+	"github.com/google/netstack/tcpip/stack"
+	"github.com/google/netstack/tcpip/transport/tcp"
+	"github.com/google/netstack/tcpip/transport/udp"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Timers of the protocol
+const (
+	RekeyAfterTime    = 120 * time.Second
+	RejectAfterTime   = 180 * time.Second
+	RekeyTimeout      = 5 * time.Second
+	RekeyAttemptTime  = 90 * time.Second
+	RejectAfterMsgs   = 1<<64 - 1<<13 - 1
+	RekeyAfterMsgs    = 1 << 60
+	DefaultMTU        = 1408
+	maxQueuedPackets  = 1024
+	handshakeInterval = 1 * time.Second
+)
+
+const nicID = 1
+
+// Config of a userspace wireguard device with a single peer
+type Config struct {
+	PrivateKey   Key
+	PeerPublic   Key
+	PresharedKey Key
+	// Endpoint of the peer, the device waits for the peer to initiate when it is nil
+	Endpoint   net.Addr
+	Addresses  []net.IP
+	AllowedIPs []*net.IPNet
+	Reserved   [3]byte
+	MTU        int
+}
+
+// keypair is the session of a finished handshake
+type keypair struct {
+	send        cipher.AEAD
+	recv        cipher.AEAD
+	sendCounter uint64
+	filter      replayFilter
+	localIndex  uint32
+	remoteIndex uint32
+	created     time.Time
+	initiator   bool
+}
+
+// Device is a wireguard peer running a userspace ip stack, connections are
+// dialed from the stack and tunneled to the peer through conn
+type Device struct {
+	keys       *staticKeys
+	reserved   [3]byte
+	allowedIPs []*net.IPNet
+	conn       net.PacketConn
+	stack      *stack.Stack
+	link       *channel.Endpoint
+
+	mux            sync.Mutex
+	endpoint       net.Addr
+	handshake      *handshake
+	lastInitiation time.Time
+	firstAttempt   time.Time
+	lastMAC1       []byte
+	cookie         []byte
+	cookieTime     time.Time
+	lastTimestamp  []byte
+	current        *keypair
+	previous       *keypair
+	next           *keypair
+	queue          [][]byte
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDevice brings up the device over conn
+func NewDevice(conn net.PacketConn, config Config) (*Device, error) {
+	keys, err := newStaticKeys(config.PrivateKey, config.PeerPublic, config.PresharedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	mtu := config.MTU
+	if mtu == 0 {
+		mtu = DefaultMTU
+	}
+
+	allowedIPs := config.AllowedIPs
+	if len(allowedIPs) == 0 {
+		_, v4, _ := net.ParseCIDR("0.0.0.0/0")
+		_, v6, _ := net.ParseCIDR("::/0")
+		allowedIPs = []*net.IPNet{v4, v6}
+	}
+
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocol{ipv4.NewProtocol(), ipv6.NewProtocol()},
+		TransportProtocols: []stack.TransportProtocol{tcp.NewProtocol(), udp.NewProtocol()},
+		HandleLocal:        true,
+	})
+	link := channel.New(maxQueuedPackets, uint32(mtu), "")
+	if err := s.CreateNIC(nicID, link); err != nil {
+		return nil, fmt.Errorf("wireguard: create nic error: %s", err)
+	}
+
+	var routes []tcpip.Route
+	for _, ip := range config.Addresses {
+		protocol, addr, prefix := ipv4.ProtocolNumber, tcpip.Address(ip.To4()), 32
+		if ip.To4() == nil {
+			protocol, addr, prefix = ipv6.ProtocolNumber, tcpip.Address(ip.To16()), 128
+		}
+
+		if err := s.AddProtocolAddress(nicID, tcpip.ProtocolAddress{
+			Protocol:          protocol,
+			AddressWithPrefix: tcpip.AddressWithPrefix{Address: addr, PrefixLen: prefix},
+		}); err != nil {
+			return nil, fmt.Errorf("wireguard: add address %s error: %s", ip, err)
+		}
+	}
+	for _, ipnet := range allowedIPs {
+		subnet, err := tcpip.NewSubnet(tcpip.Address(ipnet.IP), tcpip.AddressMask(ipnet.Mask))
+		if err != nil {
+			return nil, fmt.Errorf("wireguard: allowed ip %s error: %w", ipnet, err)
+		}
+		routes = append(routes, tcpip.Route{Destination: subnet, NIC: nicID})
+	}
+	s.SetRouteTable(routes)
+
+	d := &Device{
+		keys:       keys,
+		reserved:   config.Reserved,
+		allowedIPs: allowedIPs,
+		conn:       conn,
+		stack:      s,
+		link:       link,
+		endpoint:   config.Endpoint,
+		closed:     make(chan struct{}),
+	}
+
+	go d.readLoop()
+	go d.writeLoop()
+	go d.handshakeLoop()
+	return d, nil
+}
+
+// DialContext dials a tcp connection to addr through the peer
+func (d *Device) DialContext(ctx context.Context, ip net.IP, port int) (net.Conn, error) {
+	addr, protocol := fullAddress(ip, port)
+	return gonet.DialContextTCP(ctx, d.stack, addr, protocol)
+}
+
+// ListenPacket return an unconnected udp socket of the stack, its packets go
+// through the peer
+func (d *Device) ListenPacket(v6 bool) (net.PacketConn, error) {
+	protocol := ipv4.ProtocolNumber
+	if v6 {
+		protocol = ipv6.ProtocolNumber
+	}
+	return gonet.DialUDP(d.stack, nil, nil, protocol)
+}
+
+func fullAddress(ip net.IP, port int) (tcpip.FullAddress, tcpip.NetworkProtocolNumber) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return tcpip.FullAddress{NIC: nicID, Addr: tcpip.Address(ip4), Port: uint16(port)}, ipv4.ProtocolNumber
+	}
+	return tcpip.FullAddress{NIC: nicID, Addr: tcpip.Address(ip.To16()), Port: uint16(port)}, ipv6.ProtocolNumber
+}
+
+// Done is closed once the device is closed, by Close or by a read error of
+// the underlying conn
+func (d *Device) Done() <-chan struct{} {
+	return d.closed
+}
+
+// Close shuts the device down, it doesn't close the underlying conn
+func (d *Device) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+		d.stack.Close()
+	})
+	return nil
+}
+
+func (d *Device) allowed(ip net.IP) bool {
+	for _, ipnet := range d.allowedIPs {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Device) writeLoop() {
+	for {
+		select {
+		case <-d.closed:
+			return
+		case info := <-d.link.C:
+			packet := append(info.Pkt.Header.View(), info.Pkt.Data.ToView()...)
+			if dst := destinationIP(packet); dst == nil || !d.allowed(dst) {
+				continue
+			}
+			d.send(packet)
+		}
+	}
+}
+
+// send encrypts packet with the current keypair, the packet is queued until
+// the handshake finishes when there is no usable keypair
+func (d *Device) send(packet []byte) {
+	d.mux.Lock()
+	kp := d.current
+	now := time.Now()
+	if kp == nil || now.Sub(kp.created) > RejectAfterTime || atomic.LoadUint64(&kp.sendCounter) >= RejectAfterMsgs {
+		if len(d.queue) < maxQueuedPackets {
+			d.queue = append(d.queue, packet)
+		}
+		d.initiate(now)
+		d.mux.Unlock()
+		return
+	}
+
+	if kp.initiator && (now.Sub(kp.created) > RekeyAfterTime || atomic.LoadUint64(&kp.sendCounter) >= RekeyAfterMsgs) {
+		d.initiate(now)
+	}
+	endpoint := d.endpoint
+	d.mux.Unlock()
+
+	d.write(kp, packet, endpoint)
+}
+
+func (d *Device) write(kp *keypair, packet []byte, endpoint net.Addr) {
+	if endpoint == nil {
+		return
+	}
+
+	// pad to a multiple of 16 bytes
+	padded := make([]byte, (len(packet)+15)/16*16)
+	copy(padded, packet)
+
+	counter := atomic.AddUint64(&kp.sendCounter, 1) - 1
+	msg := make([]byte, MessageTransportHeaderSize, MessageTransportHeaderSize+len(padded)+tagSize)
+	msg[0] = MessageTransportType
+	binary.LittleEndian.PutUint32(msg[4:8], kp.remoteIndex)
+	binary.LittleEndian.PutUint64(msg[8:16], counter)
+
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	msg = kp.send.Seal(msg, nonce[:], padded, nil)
+	d.writeMessage(msg, endpoint)
+}
+
+func (d *Device) writeMessage(msg []byte, endpoint net.Addr) {
+	copy(msg[1:4], d.reserved[:])
+	d.conn.WriteTo(msg, endpoint)
+}
+
+// initiate sends a handshake initiation unless one was sent within RekeyTimeout,
+// d.mux must be held
+func (d *Device) initiate(now time.Time) {
+	if d.endpoint == nil || now.Sub(d.lastInitiation) < RekeyTimeout {
+		return
+	}
+
+	hs, msg, err := d.keys.createInitiation(now)
+	if err != nil {
+		return
+	}
+
+	var cookie []byte
+	if now.Sub(d.cookieTime) < RekeyAfterTime {
+		cookie = d.cookie
+	}
+	addMACs(msg, d.keys.peerMAC1Key, cookie)
+
+	if d.firstAttempt.IsZero() {
+		d.firstAttempt = now
+	}
+	d.handshake = hs
+	d.lastInitiation = now
+	d.lastMAC1 = append([]byte{}, msg[116:132]...)
+	d.writeMessage(msg, d.endpoint)
+}
+
+// handshakeLoop retransmits the initiation while packets are waiting for a
+// keypair, and gives up after RekeyAttemptTime
+func (d *Device) handshakeLoop() {
+	ticker := time.NewTicker(handshakeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.closed:
+			return
+		case now := <-ticker.C:
+			d.mux.Lock()
+			if len(d.queue) > 0 {
+				if now.Sub(d.firstAttempt) > RekeyAttemptTime {
+					d.queue = nil
+					d.firstAttempt = time.Time{}
+				} else {
+					d.initiate(now)
+				}
+			}
+			d.mux.Unlock()
+		}
+	}
+}
+
+func (d *Device) readLoop() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-d.closed:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			d.Close()
+			return
+		}
+		if n < 4 {
+			continue
+		}
+
+		// the reserved bytes are not covered by the macs
+		msg := buf[:n]
+		msg[1], msg[2], msg[3] = 0, 0, 0
+		switch msg[0] {
+		case MessageInitiationType:
+			d.handleInitiation(msg, addr)
+		case MessageResponseType:
+			d.handleResponse(msg, addr)
+		case MessageCookieReplyType:
+			d.handleCookieReply(msg)
+		case MessageTransportType:
+			d.handleTransport(msg, addr)
+		}
+	}
+}
+
+func (d *Device) handleInitiation(msg []byte, addr net.Addr) {
+	if len(msg) != MessageInitiationSize || !checkMAC1(msg, d.keys.mac1Key) {
+		return
+	}
+
+	hs, timestamp, err := d.keys.consumeInitiation(msg)
+	if err != nil {
+		return
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.lastTimestamp != nil && bytes.Compare(timestamp, d.lastTimestamp) <= 0 {
+		return
+	}
+
+	resp, err := d.keys.createResponse(hs)
+	if err != nil {
+		return
+	}
+	addMACs(resp, d.keys.peerMAC1Key, nil)
+
+	d.lastTimestamp = timestamp
+	d.endpoint = addr
+	d.next = newKeypair(hs, false)
+	d.writeMessage(resp, addr)
+}
+
+func (d *Device) handleResponse(msg []byte, addr net.Addr) {
+	if len(msg) != MessageResponseSize || !checkMAC1(msg, d.keys.mac1Key) {
+		return
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.handshake == nil {
+		return
+	}
+	if err := d.keys.consumeResponse(d.handshake, msg); err != nil {
+		return
+	}
+
+	kp := newKeypair(d.handshake, true)
+	d.handshake = nil
+	d.firstAttempt = time.Time{}
+	d.endpoint = addr
+	d.previous, d.current, d.next = d.current, kp, nil
+
+	// confirm the session with the queued packets or a keepalive
+	queue := d.queue
+	d.queue = nil
+	if len(queue) == 0 {
+		queue = [][]byte{nil}
+	}
+	for _, packet := range queue {
+		d.write(kp, packet, addr)
+	}
+}
+
+func (d *Device) handleCookieReply(msg []byte) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.lastMAC1 == nil {
+		return
+	}
+	cookie, err := d.keys.consumeCookieReply(msg, d.lastMAC1)
+	if err != nil {
+		return
+	}
+	d.cookie = cookie
+	d.cookieTime = time.Now()
+	// the next initiation carries the cookie as mac2
+	d.lastInitiation = time.Time{}
+}
+
+func (d *Device) handleTransport(msg []byte, addr net.Addr) {
+	if len(msg) < MessageKeepaliveSize {
+		return
+	}
+
+	index := binary.LittleEndian.Uint32(msg[4:8])
+	d.mux.Lock()
+	var kp *keypair
+	for _, candidate := range []*keypair{d.current, d.next, d.previous} {
+		if candidate != nil && candidate.localIndex == index {
+			kp = candidate
+			break
+		}
+	}
+	d.mux.Unlock()
+
+	if kp == nil || time.Since(kp.created) > RejectAfterTime {
+		return
+	}
+
+	counter := binary.LittleEndian.Uint64(msg[8:16])
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	packet, err := kp.recv.Open(nil, nonce[:], msg[MessageTransportHeaderSize:], nil)
+	if err != nil || !kp.filter.check(counter) {
+		return
+	}
+
+	d.mux.Lock()
+	d.endpoint = addr
+	var queue [][]byte
+	if kp == d.next {
+		// the first packet of the peer confirms the responder session
+		d.previous, d.current, d.next = d.current, kp, nil
+		queue = d.queue
+		d.queue = nil
+		d.firstAttempt = time.Time{}
+	}
+	d.mux.Unlock()
+
+	for _, p := range queue {
+		d.write(kp, p, addr)
+	}
+
+	// keepalive
+	if len(packet) == 0 {
+		return
+	}
+
+	packet, protocol := trimPacket(packet)
+	if packet == nil {
+		return
+	}
+	if src := sourceIP(packet); src == nil || !d.allowed(src) {
+		return
+	}
+
+	d.link.InjectInbound(protocol, tcpip.PacketBuffer{
+		Data: buffer.View(packet).ToVectorisedView(),
+	})
+}
+
+func newKeypair(hs *handshake, initiator bool) *keypair {
+	send, recv := hs.transportKeys(initiator)
+	sendAEAD, _ := chacha20poly1305.New(send[:])
+	recvAEAD, _ := chacha20poly1305.New(recv[:])
+	return &keypair{
+		send:        sendAEAD,
+		recv:        recvAEAD,
+		localIndex:  hs.localIndex,
+		remoteIndex: hs.remoteIndex,
+		created:     time.Now(),
+		initiator:   initiator,
+	}
+}
+
+// trimPacket removes the padding of a decrypted ip packet
+func trimPacket(packet []byte) ([]byte, tcpip.NetworkProtocolNumber) {
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return nil, 0
+		}
+		length := int(binary.BigEndian.Uint16(packet[2:4]))
+		if length > len(packet) {
+			return nil, 0
+		}
+		return packet[:length], ipv4.ProtocolNumber
+	case 6:
+		if len(packet) < 40 {
+			return nil, 0
+		}
+		length := int(binary.BigEndian.Uint16(packet[4:6])) + 40
+		if length > len(packet) {
+			return nil, 0
+		}
+		return packet[:length], ipv6.ProtocolNumber
+	}
+	return nil, 0
+}
+
+func sourceIP(packet []byte) net.IP {
+	switch packet[0] >> 4 {
+	case 4:
+		return net.IP(packet[12:16])
+	case 6:
+		return net.IP(packet[8:24])
+	}
+	return nil
+}
+
+func destinationIP(packet []byte) net.IP {
+	switch {
+	case len(packet) >= 20 && packet[0]>>4 == 4:
+		return net.IP(packet[16:20])
+	case len(packet) >= 40 && packet[0]>>4 == 6:
+		return net.IP(packet[24:40])
+	}
+	return nil
+}
+
+// replayFilter is the sliding window of the received counters
+type replayFilter struct {
+	mux    sync.Mutex
+	last   uint64
+	bitmap [replayWindowSize / 64]uint64
+}
+
+const replayWindowSize = 2048
+
+func (f *replayFilter) check(counter uint64) bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if counter >= RejectAfterMsgs {
+		return false
+	}
+
+	if counter > f.last {
+		// clear the words between the last counter and this one
+		current, next := f.last/64, counter/64
+		if next-current >= uint64(len(f.bitmap)) {
+			f.bitmap = [replayWindowSize / 64]uint64{}
+		} else {
+			for i := current + 1; i <= next; i++ {
+				f.bitmap[i%uint64(len(f.bitmap))] = 0
+			}
+		}
+		f.last = counter
+	} else if f.last-counter >= replayWindowSize-64 {
+		return false
+	}
+
+	word, bit := (counter/64)%uint64(len(f.bitmap)), counter%64
+	if f.bitmap[word]&(1<<bit) != 0 {
+		return false
+	}
+	f.bitmap[word] |= 1 << bit
+	return true
+}
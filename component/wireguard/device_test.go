@@ -0,0 +1,171 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/netstack/tcpip"
+	"github.com/google/netstack/tcpip/adapters/gonet"
+	"github.com/google/netstack/tcpip/network/ipv4"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordConn records the reserved bytes of the sent messages
+type recordConn struct {
+	net.PacketConn
+	mux      sync.Mutex
+	reserved [][]byte
+}
+
+func (rc *recordConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	rc.mux.Lock()
+	rc.reserved = append(rc.reserved, append([]byte{}, b[1:4]...))
+	rc.mux.Unlock()
+	return rc.PacketConn.WriteTo(b, addr)
+}
+
+func newTestDevices(t *testing.T) (*Device, *Device, *recordConn, func()) {
+	clientKey, serverKey := NewPrivateKey(), NewPrivateKey()
+
+	serverConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server, err := NewDevice(serverConn, Config{
+		PrivateKey: serverKey,
+		PeerPublic: clientKey.PublicKey(),
+		Addresses:  []net.IP{net.ParseIP("10.0.0.1")},
+	})
+	assert.Nil(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	clientConn := &recordConn{PacketConn: conn}
+	_, allowed, _ := net.ParseCIDR("10.0.0.0/24")
+	client, err := NewDevice(clientConn, Config{
+		PrivateKey: clientKey,
+		PeerPublic: serverKey.PublicKey(),
+		Endpoint:   serverConn.LocalAddr(),
+		Addresses:  []net.IP{net.ParseIP("10.0.0.2")},
+		AllowedIPs: []*net.IPNet{allowed},
+		Reserved:   [3]byte{1, 2, 3},
+		MTU:        1280,
+	})
+	assert.Nil(t, err)
+
+	return client, server, clientConn, func() {
+		client.Close()
+		server.Close()
+		conn.Close()
+		serverConn.Close()
+	}
+}
+
+func TestDevice_TCP(t *testing.T) {
+	client, server, clientConn, closer := newTestDevices(t)
+	defer closer()
+
+	l, err := gonet.NewListener(server.stack, tcpip.FullAddress{NIC: nicID, Addr: tcpip.Address(net.ParseIP("10.0.0.1").To4()), Port: 80}, ipv4.ProtocolNumber)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := client.DialContext(ctx, net.ParseIP("10.0.0.1"), 80)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// larger than the mtu
+	msg := bytes.Repeat([]byte("wireguard"), 1000)
+	go c.Write(msg)
+
+	buf := make([]byte, len(msg))
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, buf)
+
+	clientConn.mux.Lock()
+	for _, reserved := range clientConn.reserved {
+		assert.Equal(t, []byte{1, 2, 3}, reserved)
+	}
+	clientConn.mux.Unlock()
+}
+
+func TestDevice_UDP(t *testing.T) {
+	client, server, _, closer := newTestDevices(t)
+	defer closer()
+
+	laddr := tcpip.FullAddress{NIC: nicID, Addr: tcpip.Address(net.ParseIP("10.0.0.1").To4()), Port: 53}
+	echo, err := gonet.DialUDP(server.stack, &laddr, nil, ipv4.ProtocolNumber)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	pc, err := client.ListenPacket(false)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer pc.Close()
+
+	target := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53}
+	_, err = pc.WriteTo([]byte("hello udp"), target)
+	assert.Nil(t, err)
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, addr, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello udp", string(buf[:n]))
+	assert.Equal(t, target.String(), addr.String())
+}
+
+func TestDevice_AllowedIPs(t *testing.T) {
+	client, _, _, closer := newTestDevices(t)
+	defer closer()
+
+	// outside of the allowed ips there is no route
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.DialContext(ctx, net.ParseIP("1.1.1.1"), 80)
+	assert.NotNil(t, err)
+}
+
+func TestReplayFilter(t *testing.T) {
+	f := &replayFilter{}
+	assert.True(t, f.check(0))
+	assert.False(t, f.check(0))
+	assert.True(t, f.check(5))
+	assert.True(t, f.check(3))
+	assert.False(t, f.check(3))
+	assert.True(t, f.check(5000))
+	assert.False(t, f.check(5))
+	assert.True(t, f.check(4000))
+	assert.False(t, f.check(4000))
+}
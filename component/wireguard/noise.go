@@ -0,0 +1,421 @@
+package wireguard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Message types
+const (
+	MessageInitiationType  byte = 1
+	MessageResponseType    byte = 2
+	MessageCookieReplyType byte = 3
+	MessageTransportType   byte = 4
+)
+
+// Message sizes
+const (
+	MessageInitiationSize      = 148
+	MessageResponseSize        = 92
+	MessageCookieReplySize     = 64
+	MessageTransportHeaderSize = 16
+	MessageKeepaliveSize       = MessageTransportHeaderSize + tagSize
+
+	tagSize = 16
+)
+
+const (
+	noiseConstruction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	wgIdentifier      = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	wgLabelMAC1       = "mac1----"
+	wgLabelCookie     = "cookie--"
+)
+
+var (
+	errInvalidKey     = errors.New("wireguard: invalid key")
+	errInvalidMessage = errors.New("wireguard: invalid message")
+	errUnexpectedPeer = errors.New("wireguard: unexpected peer")
+
+	initialChainKey [blake2s.Size]byte
+	initialHash     [blake2s.Size]byte
+)
+
+func init() {
+	initialChainKey = blake2s.Sum256([]byte(noiseConstruction))
+	initialHash = mixHash(initialChainKey, []byte(wgIdentifier))
+}
+
+// Key is a curve25519 key or a pre-shared key
+type Key [32]byte
+
+// ParseKey decodes a base64 encoded key
+func ParseKey(s string) (Key, error) {
+	var k Key
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return k, fmt.Errorf("wireguard: decode key error: %w", err)
+	}
+	if len(b) != len(k) {
+		return k, errInvalidKey
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// NewPrivateKey return a random private key
+func NewPrivateKey() Key {
+	var k Key
+	rand.Read(k[:])
+	k[0] &= 248
+	k[31] = (k[31] & 127) | 64
+	return k
+}
+
+// PublicKey return the public key of a private key
+func (k Key) PublicKey() Key {
+	var pub Key
+	curve25519.ScalarBaseMult((*[32]byte)(&pub), (*[32]byte)(&k))
+	return pub
+}
+
+func (k Key) String() string {
+	return base64.StdEncoding.EncodeToString(k[:])
+}
+
+func (k Key) isZero() bool {
+	var zero Key
+	return subtle.ConstantTimeCompare(k[:], zero[:]) == 1
+}
+
+// sharedSecret is the curve25519 DH, rejecting the low order points
+func sharedSecret(private, public Key) (Key, error) {
+	var ss Key
+	curve25519.ScalarMult((*[32]byte)(&ss), (*[32]byte)(&private), (*[32]byte)(&public))
+	if ss.isZero() {
+		return ss, errInvalidKey
+	}
+	return ss, nil
+}
+
+func newBlake2s() hash.Hash {
+	h, _ := blake2s.New256(nil)
+	return h
+}
+
+func hmacSum(key []byte, inputs ...[]byte) [blake2s.Size]byte {
+	var sum [blake2s.Size]byte
+	mac := hmac.New(newBlake2s, key)
+	for _, in := range inputs {
+		mac.Write(in)
+	}
+	mac.Sum(sum[:0])
+	return sum
+}
+
+// kdf return n keys derived from key and input with HMAC-BLAKE2s
+func kdf(key, input []byte, n int) [][blake2s.Size]byte {
+	prk := hmacSum(key, input)
+	keys := make([][blake2s.Size]byte, n)
+	prev := []byte{}
+	for i := 0; i < n; i++ {
+		keys[i] = hmacSum(prk[:], prev, []byte{byte(i + 1)})
+		prev = keys[i][:]
+	}
+	return keys
+}
+
+func mixHash(h [blake2s.Size]byte, data []byte) [blake2s.Size]byte {
+	hash := newBlake2s()
+	hash.Write(h[:])
+	hash.Write(data)
+
+	var sum [blake2s.Size]byte
+	hash.Sum(sum[:0])
+	return sum
+}
+
+// mac is the keyed BLAKE2s-128 of the mac fields
+func mac(key, data []byte) [blake2s.Size128]byte {
+	var sum [blake2s.Size128]byte
+	h, _ := blake2s.New128(key)
+	h.Write(data)
+	h.Sum(sum[:0])
+	return sum
+}
+
+func seal(key [blake2s.Size]byte, plaintext, ad []byte) []byte {
+	aead, _ := chacha20poly1305.New(key[:])
+	var nonce [chacha20poly1305.NonceSize]byte
+	return aead.Seal(nil, nonce[:], plaintext, ad)
+}
+
+func open(key [blake2s.Size]byte, ciphertext, ad []byte) ([]byte, error) {
+	aead, _ := chacha20poly1305.New(key[:])
+	var nonce [chacha20poly1305.NonceSize]byte
+	return aead.Open(nil, nonce[:], ciphertext, ad)
+}
+
+// tai64n encodes t as the handshake timestamp
+func tai64n(t time.Time) []byte {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint64(b, uint64(0x400000000000000a+t.Unix()))
+	binary.BigEndian.PutUint32(b[8:], uint32(t.Nanosecond()))
+	return b
+}
+
+func newIndex() uint32 {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return binary.LittleEndian.Uint32(b)
+}
+
+// staticKeys of a device and its peer
+type staticKeys struct {
+	private      Key
+	public       Key
+	peerPublic   Key
+	presharedKey Key
+	staticStatic Key
+
+	// mac1 and cookie keys of the messages sent to the peer
+	peerMAC1Key   [blake2s.Size]byte
+	peerCookieKey [blake2s.Size]byte
+	// mac1 key of the messages sent by the peer
+	mac1Key [blake2s.Size]byte
+}
+
+func newStaticKeys(private, peerPublic, presharedKey Key) (*staticKeys, error) {
+	ss, err := sharedSecret(private, peerPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	public := private.PublicKey()
+	return &staticKeys{
+		private:       private,
+		public:        public,
+		peerPublic:    peerPublic,
+		presharedKey:  presharedKey,
+		staticStatic:  ss,
+		peerMAC1Key:   blake2s.Sum256(append([]byte(wgLabelMAC1), peerPublic[:]...)),
+		peerCookieKey: blake2s.Sum256(append([]byte(wgLabelCookie), peerPublic[:]...)),
+		mac1Key:       blake2s.Sum256(append([]byte(wgLabelMAC1), public[:]...)),
+	}, nil
+}
+
+// handshake is the state of a Noise_IKpsk2 handshake
+type handshake struct {
+	hash            [blake2s.Size]byte
+	chainKey        [blake2s.Size]byte
+	localEphemeral  Key
+	remoteEphemeral Key
+	localIndex      uint32
+	remoteIndex     uint32
+}
+
+// createInitiation return the initiation message without the macs
+func (sk *staticKeys) createInitiation(now time.Time) (*handshake, []byte, error) {
+	hs := &handshake{
+		chainKey:       initialChainKey,
+		hash:           mixHash(initialHash, sk.peerPublic[:]),
+		localEphemeral: NewPrivateKey(),
+		localIndex:     newIndex(),
+	}
+
+	msg := make([]byte, MessageInitiationSize)
+	msg[0] = MessageInitiationType
+	binary.LittleEndian.PutUint32(msg[4:8], hs.localIndex)
+
+	ephemeral := hs.localEphemeral.PublicKey()
+	copy(msg[8:40], ephemeral[:])
+	hs.chainKey = kdf(hs.chainKey[:], ephemeral[:], 1)[0]
+	hs.hash = mixHash(hs.hash, ephemeral[:])
+
+	es, err := sharedSecret(hs.localEphemeral, sk.peerPublic)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := kdf(hs.chainKey[:], es[:], 2)
+	hs.chainKey = keys[0]
+	static := seal(keys[1], sk.public[:], hs.hash[:])
+	copy(msg[40:88], static)
+	hs.hash = mixHash(hs.hash, static)
+
+	keys = kdf(hs.chainKey[:], sk.staticStatic[:], 2)
+	hs.chainKey = keys[0]
+	timestamp := seal(keys[1], tai64n(now), hs.hash[:])
+	copy(msg[88:116], timestamp)
+	hs.hash = mixHash(hs.hash, timestamp)
+
+	return hs, msg, nil
+}
+
+// consumeInitiation validates the initiation of the peer, it return the
+// handshake state and the timestamp of the message
+func (sk *staticKeys) consumeInitiation(msg []byte) (*handshake, []byte, error) {
+	if len(msg) != MessageInitiationSize || msg[0] != MessageInitiationType {
+		return nil, nil, errInvalidMessage
+	}
+
+	hs := &handshake{
+		chainKey:    initialChainKey,
+		hash:        mixHash(initialHash, sk.public[:]),
+		remoteIndex: binary.LittleEndian.Uint32(msg[4:8]),
+	}
+
+	copy(hs.remoteEphemeral[:], msg[8:40])
+	hs.chainKey = kdf(hs.chainKey[:], msg[8:40], 1)[0]
+	hs.hash = mixHash(hs.hash, msg[8:40])
+
+	es, err := sharedSecret(sk.private, hs.remoteEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := kdf(hs.chainKey[:], es[:], 2)
+	hs.chainKey = keys[0]
+	static, err := open(keys[1], msg[40:88], hs.hash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(static, sk.peerPublic[:]) != 1 {
+		return nil, nil, errUnexpectedPeer
+	}
+	hs.hash = mixHash(hs.hash, msg[40:88])
+
+	keys = kdf(hs.chainKey[:], sk.staticStatic[:], 2)
+	hs.chainKey = keys[0]
+	timestamp, err := open(keys[1], msg[88:116], hs.hash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	hs.hash = mixHash(hs.hash, msg[88:116])
+
+	return hs, timestamp, nil
+}
+
+// createResponse return the response message to a consumed initiation
+// without the macs
+func (sk *staticKeys) createResponse(hs *handshake) ([]byte, error) {
+	hs.localEphemeral = NewPrivateKey()
+	hs.localIndex = newIndex()
+
+	msg := make([]byte, MessageResponseSize)
+	msg[0] = MessageResponseType
+	binary.LittleEndian.PutUint32(msg[4:8], hs.localIndex)
+	binary.LittleEndian.PutUint32(msg[8:12], hs.remoteIndex)
+
+	ephemeral := hs.localEphemeral.PublicKey()
+	copy(msg[12:44], ephemeral[:])
+	hs.chainKey = kdf(hs.chainKey[:], ephemeral[:], 1)[0]
+	hs.hash = mixHash(hs.hash, ephemeral[:])
+
+	ee, err := sharedSecret(hs.localEphemeral, hs.remoteEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	hs.chainKey = kdf(hs.chainKey[:], ee[:], 1)[0]
+
+	se, err := sharedSecret(hs.localEphemeral, sk.peerPublic)
+	if err != nil {
+		return nil, err
+	}
+	hs.chainKey = kdf(hs.chainKey[:], se[:], 1)[0]
+
+	keys := kdf(hs.chainKey[:], sk.presharedKey[:], 3)
+	hs.chainKey = keys[0]
+	hs.hash = mixHash(hs.hash, keys[1][:])
+	empty := seal(keys[2], nil, hs.hash[:])
+	copy(msg[44:60], empty)
+	hs.hash = mixHash(hs.hash, empty)
+
+	return msg, nil
+}
+
+// consumeResponse validates the response to the initiation of hs
+func (sk *staticKeys) consumeResponse(hs *handshake, msg []byte) error {
+	if len(msg) != MessageResponseSize || msg[0] != MessageResponseType {
+		return errInvalidMessage
+	}
+	if binary.LittleEndian.Uint32(msg[8:12]) != hs.localIndex {
+		return errInvalidMessage
+	}
+
+	var remoteEphemeral Key
+	copy(remoteEphemeral[:], msg[12:44])
+	chainKey := kdf(hs.chainKey[:], remoteEphemeral[:], 1)[0]
+	h := mixHash(hs.hash, remoteEphemeral[:])
+
+	ee, err := sharedSecret(hs.localEphemeral, remoteEphemeral)
+	if err != nil {
+		return err
+	}
+	chainKey = kdf(chainKey[:], ee[:], 1)[0]
+
+	se, err := sharedSecret(sk.private, remoteEphemeral)
+	if err != nil {
+		return err
+	}
+	chainKey = kdf(chainKey[:], se[:], 1)[0]
+
+	keys := kdf(chainKey[:], sk.presharedKey[:], 3)
+	h = mixHash(h, keys[1][:])
+	if _, err := open(keys[2], msg[44:60], h[:]); err != nil {
+		return err
+	}
+
+	hs.remoteEphemeral = remoteEphemeral
+	hs.remoteIndex = binary.LittleEndian.Uint32(msg[4:8])
+	hs.chainKey = keys[0]
+	hs.hash = mixHash(h, msg[44:60])
+	return nil
+}
+
+// transportKeys derives the send and receive keys of a finished handshake
+func (hs *handshake) transportKeys(initiator bool) (send, recv [blake2s.Size]byte) {
+	keys := kdf(hs.chainKey[:], nil, 2)
+	if initiator {
+		return keys[0], keys[1]
+	}
+	return keys[1], keys[0]
+}
+
+// addMACs fills mac1 and, with a valid cookie, mac2 of a handshake message
+func addMACs(msg []byte, mac1Key [blake2s.Size]byte, cookie []byte) {
+	offset := len(msg) - 2*blake2s.Size128
+	mac1 := mac(mac1Key[:], msg[:offset])
+	copy(msg[offset:], mac1[:])
+
+	if cookie != nil {
+		mac2 := mac(cookie, msg[:offset+blake2s.Size128])
+		copy(msg[offset+blake2s.Size128:], mac2[:])
+	}
+}
+
+// checkMAC1 verifies the mac1 of a handshake message sent to us
+func checkMAC1(msg []byte, mac1Key [blake2s.Size]byte) bool {
+	offset := len(msg) - 2*blake2s.Size128
+	expected := mac(mac1Key[:], msg[:offset])
+	return hmac.Equal(expected[:], msg[offset:offset+blake2s.Size128])
+}
+
+// consumeCookieReply decrypts the cookie of a cookie reply to the message
+// with lastMAC1
+func (sk *staticKeys) consumeCookieReply(msg, lastMAC1 []byte) ([]byte, error) {
+	if len(msg) != MessageCookieReplySize || msg[0] != MessageCookieReplyType {
+		return nil, errInvalidMessage
+	}
+
+	aead, _ := chacha20poly1305.NewX(sk.peerCookieKey[:])
+	return aead.Open(nil, msg[8:32], msg[32:], lastMAC1)
+}
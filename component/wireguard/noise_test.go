@@ -0,0 +1,56 @@
+package wireguard
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoise_Handshake(t *testing.T) {
+	initiatorKey, responderKey := NewPrivateKey(), NewPrivateKey()
+	var psk Key
+	copy(psk[:], bytes.Repeat([]byte{1}, 32))
+
+	initiator, err := newStaticKeys(initiatorKey, responderKey.PublicKey(), psk)
+	assert.Nil(t, err)
+	responder, err := newStaticKeys(responderKey, initiatorKey.PublicKey(), psk)
+	assert.Nil(t, err)
+
+	ihs, msg, err := initiator.createInitiation(time.Now())
+	assert.Nil(t, err)
+	addMACs(msg, initiator.peerMAC1Key, nil)
+	assert.True(t, checkMAC1(msg, responder.mac1Key))
+
+	rhs, timestamp, err := responder.consumeInitiation(msg)
+	assert.Nil(t, err)
+	assert.Len(t, timestamp, 12)
+
+	resp, err := responder.createResponse(rhs)
+	assert.Nil(t, err)
+	addMACs(resp, responder.peerMAC1Key, nil)
+	assert.True(t, checkMAC1(resp, initiator.mac1Key))
+	assert.Nil(t, initiator.consumeResponse(ihs, resp))
+
+	iSend, iRecv := ihs.transportKeys(true)
+	rSend, rRecv := rhs.transportKeys(false)
+	assert.Equal(t, iSend, rRecv)
+	assert.Equal(t, iRecv, rSend)
+	assert.Equal(t, ihs.remoteIndex, rhs.localIndex)
+	assert.Equal(t, rhs.remoteIndex, ihs.localIndex)
+
+	// another peer can't complete the handshake
+	stranger, _ := newStaticKeys(NewPrivateKey(), responderKey.PublicKey(), psk)
+	_, msg, _ = stranger.createInitiation(time.Now())
+	_, _, err = responder.consumeInitiation(msg)
+	assert.NotNil(t, err)
+
+	// the pre-shared keys must match
+	_, msg, _ = initiator.createInitiation(time.Now())
+	rhs, _, _ = responder.consumeInitiation(msg)
+	responder.presharedKey = Key{}
+	resp, _ = responder.createResponse(rhs)
+	ihs, msg, _ = initiator.createInitiation(time.Now())
+	assert.NotNil(t, initiator.consumeResponse(ihs, resp))
+}
@@ -73,15 +73,16 @@ type Experimental struct {
 
 // Config is clash config manager
 type Config struct {
-	General      *General
-	Tun          *Tun
-	DNS          *DNS
-	Experimental *Experimental
-	Hosts        *trie.Trie
-	Rules        []C.Rule
-	Users        []auth.AuthUser
-	Proxies      map[string]C.Proxy
-	Providers    map[string]provider.ProxyProvider
+	General       *General
+	Tun           *Tun
+	DNS           *DNS
+	Experimental  *Experimental
+	Hosts         *trie.Trie
+	Rules         []C.Rule
+	Users         []auth.AuthUser
+	Proxies       map[string]C.Proxy
+	Providers     map[string]provider.ProxyProvider
+	RuleProviders map[string]provider.RuleProvider
 }
 
 type RawDNS struct {
@@ -116,9 +117,10 @@ type RawConfig struct {
 	Secret             string       `yaml:"secret"`
 
 	ProxyProvider map[string]map[string]interface{} `yaml:"proxy-provider"`
+	RuleProvider  map[string]map[string]interface{} `yaml:"rule-providers"`
 	Hosts         map[string]string                 `yaml:"hosts"`
 	DNS           RawDNS                            `yaml:"dns"`
-    Tun           Tun                               `yaml:"tun"`
+	Tun           Tun                               `yaml:"tun"`
 	Experimental  Experimental                      `yaml:"experimental"`
 	Proxy         []map[string]interface{}          `yaml:"Proxy"`
 	ProxyGroup    []map[string]interface{}          `yaml:"Proxy Group"`
@@ -194,20 +196,29 @@ func ParseRawConfig(rawCfg *RawConfig, baseDir string) (*Config, error) {
 	config.Proxies = proxies
 	config.Providers = providers
 
-	rules, err := parseRules(rawCfg, proxies)
+	ruleProviders, err := parseRuleProviders(rawCfg, baseDir)
 	if err != nil {
 		return nil, err
 	}
+	config.RuleProviders = ruleProviders
+
+	rules, err := parseRules(rawCfg, proxies, ruleProviders)
+	if err != nil {
+		destroyRuleProviders(ruleProviders)
+		return nil, err
+	}
 	config.Rules = rules
 
 	dnsCfg, err := parseDNS(rawCfg.DNS)
 	if err != nil {
+		destroyRuleProviders(ruleProviders)
 		return nil, err
 	}
 	config.DNS = dnsCfg
 
 	hosts, err := parseHosts(rawCfg)
 	if err != nil {
+		destroyRuleProviders(ruleProviders)
 		return nil, err
 	}
 	config.Hosts = hosts
@@ -261,6 +272,7 @@ func parseProxies(cfg *RawConfig, baseDir string) (proxies map[string]C.Proxy, p
 	proxiesConfig := cfg.Proxy
 	groupsConfig := cfg.ProxyGroup
 	providersConfig := cfg.ProxyProvider
+	dialerProxies := make(map[string]string)
 
 	defer func() {
 		// Destroy already created provider when err != nil
@@ -287,6 +299,10 @@ func parseProxies(cfg *RawConfig, baseDir string) (proxies map[string]C.Proxy, p
 		}
 		proxies[proxy.Name()] = proxy
 		proxyList = append(proxyList, proxy.Name())
+
+		if dialerProxy, ok := mapping["dialer-proxy"].(string); ok && dialerProxy != "" {
+			dialerProxies[proxy.Name()] = dialerProxy
+		}
 	}
 
 	// keep the origional order of ProxyGroups in config file
@@ -303,6 +319,11 @@ func parseProxies(cfg *RawConfig, baseDir string) (proxies map[string]C.Proxy, p
 		return nil, nil, err
 	}
 
+	// a proxy can't dial its server through itself
+	if err := dialerProxyLoopCheck(dialerProxies, groupsConfig); err != nil {
+		return nil, nil, err
+	}
+
 	// parse and initial providers
 	for name, mapping := range providersConfig {
 		if name == provider.ReservedName {
@@ -339,6 +360,15 @@ func parseProxies(cfg *RawConfig, baseDir string) (proxies map[string]C.Proxy, p
 		proxies[groupName] = outbound.NewProxy(group)
 	}
 
+	// the dialer of a proxy can be a group, so they are set after groups
+	for name, dialerProxy := range dialerProxies {
+		dialer, exist := proxies[dialerProxy]
+		if !exist {
+			return nil, nil, fmt.Errorf("Proxy %s: dialer-proxy %s not found", name, dialerProxy)
+		}
+		proxies[name].(*outbound.Proxy).SetDialer(dialer)
+	}
+
 	// initial compatible provider
 	for _, pd := range providersMap {
 		if pd.VehicleType() != provider.Compatible {
@@ -355,22 +385,58 @@ func parseProxies(cfg *RawConfig, baseDir string) (proxies map[string]C.Proxy, p
 	for _, v := range proxyList {
 		ps = append(ps, proxies[v])
 	}
-	hc := provider.NewHealthCheck(ps, "", 0)
+	hc := provider.NewHealthCheck(ps, "", 0, false)
 	pd, _ := provider.NewCompatibleProvider(provider.ReservedName, ps, hc)
 	providersMap[provider.ReservedName] = pd
 
 	global := outboundgroup.NewSelector("GLOBAL", []provider.ProxyProvider{pd})
 	proxies["GLOBAL"] = outbound.NewProxy(global)
+
 	return proxies, providersMap, nil
 }
 
-func parseRules(cfg *RawConfig, proxies map[string]C.Proxy) ([]C.Rule, error) {
+func parseRuleProviders(cfg *RawConfig, baseDir string) (map[string]provider.RuleProvider, error) {
+	providersMap := make(map[string]provider.RuleProvider)
+
+	for name, mapping := range cfg.RuleProvider {
+		pd, err := provider.ParseRuleProvider(name, mapping, baseDir)
+		if err != nil {
+			destroyRuleProviders(providersMap)
+			return nil, fmt.Errorf("RuleProvider %s: %w", name, err)
+		}
+
+		providersMap[name] = pd
+	}
+
+	for _, provider := range providersMap {
+		log.Infoln("Start initial rule provider %s", provider.Name())
+		if err := provider.Initial(); err != nil {
+			destroyRuleProviders(providersMap)
+			return nil, fmt.Errorf("RuleProvider %s: %w", provider.Name(), err)
+		}
+	}
+
+	return providersMap, nil
+}
+
+// destroyRuleProviders stops the rule providers of a config that fails to load
+func destroyRuleProviders(providers map[string]provider.RuleProvider) {
+	for _, pd := range providers {
+		pd.Destroy()
+	}
+}
+
+func parseRules(cfg *RawConfig, proxies map[string]C.Proxy, ruleProviders map[string]provider.RuleProvider) ([]C.Rule, error) {
 	rules := []C.Rule{}
 
 	rulesConfig := cfg.Rule
 	// parse rules
 	for idx, line := range rulesConfig {
-		rule := trimArr(strings.Split(line, ","))
+		rule, err := R.SplitRuleLine(line, true)
+		if err != nil {
+			return nil, fmt.Errorf("Rules[%d] [%s] error: %s", idx, line, err.Error())
+		}
+
 		var (
 			payload string
 			target  string
@@ -397,42 +463,8 @@ func parseRules(cfg *RawConfig, proxies map[string]C.Proxy) ([]C.Rule, error) {
 
 		rule = trimArr(rule)
 		params = trimArr(params)
-		var (
-			parseErr error
-			parsed   C.Rule
-		)
-
-		switch rule[0] {
-		case "DOMAIN":
-			parsed = R.NewDomain(payload, target)
-		case "DOMAIN-SUFFIX":
-			parsed = R.NewDomainSuffix(payload, target)
-		case "DOMAIN-KEYWORD":
-			parsed = R.NewDomainKeyword(payload, target)
-		case "GEOIP":
-			noResolve := R.HasNoResolve(params)
-			parsed = R.NewGEOIP(payload, target, noResolve)
-		case "IP-CIDR", "IP-CIDR6":
-			noResolve := R.HasNoResolve(params)
-			parsed, parseErr = R.NewIPCIDR(payload, target, R.WithIPCIDRNoResolve(noResolve))
-		// deprecated when bump to 1.0
-		case "SOURCE-IP-CIDR":
-			fallthrough
-		case "SRC-IP-CIDR":
-			parsed, parseErr = R.NewIPCIDR(payload, target, R.WithIPCIDRSourceIP(true), R.WithIPCIDRNoResolve(true))
-		case "SRC-PORT":
-			parsed, parseErr = R.NewPort(payload, target, true)
-		case "DST-PORT":
-			parsed, parseErr = R.NewPort(payload, target, false)
-		case "MATCH":
-			fallthrough
-		// deprecated when bump to 1.0
-		case "FINAL":
-			parsed = R.NewMatch(target)
-		default:
-			parseErr = fmt.Errorf("unsupported rule type %s", rule[0])
-		}
 
+		parsed, parseErr := parseRule(rule[0], payload, target, params, ruleProviders)
 		if parseErr != nil {
 			return nil, fmt.Errorf("Rules[%d] [%s] error: %s", idx, line, parseErr.Error())
 		}
@@ -443,6 +475,24 @@ func parseRules(cfg *RawConfig, proxies map[string]C.Proxy) ([]C.Rule, error) {
 	return rules, nil
 }
 
+func parseRule(tp, payload, target string, params []string, ruleProviders map[string]provider.RuleProvider) (C.Rule, error) {
+	switch tp {
+	case "RULE-SET":
+		pd, ok := ruleProviders[payload]
+		if !ok {
+			return nil, fmt.Errorf("rule provider [%s] not found", payload)
+		}
+		return R.NewRuleSet(pd, target, R.HasNoResolve(params)), nil
+	case "AND", "OR", "NOT":
+		// sub-rules may be logic rules or RULE-SET as well
+		return R.NewLogic(tp, payload, target, func(tp, payload string, params []string) (C.Rule, error) {
+			return parseRule(tp, payload, "", params, ruleProviders)
+		})
+	default:
+		return R.ParseRule(tp, payload, target, params)
+	}
+}
+
 func parseHosts(cfg *RawConfig) (*trie.Trie, error) {
 	tree := trie.New()
 	if len(cfg.Hosts) != 0 {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Dreamacro/clash/adapters/outboundgroup"
@@ -155,3 +156,68 @@ func proxyGroupsDagSort(groupsConfig []map[string]interface{}) error {
 	}
 	return fmt.Errorf("Loop is detected in ProxyGroup, please check following ProxyGroups: %v", loopElements)
 }
+
+// Check if the `dialer-proxy` of proxies forms a loop with each other or the
+// ProxyGroups, which means a proxy would dial its server through itself.
+// If loop is detected, return an error with the path of loop.
+func dialerProxyLoopCheck(dialerProxies map[string]string, groupsConfig []map[string]interface{}) error {
+	decoder := structure.NewDecoder(structure.Option{TagName: "group", WeaklyTypedInput: true})
+
+	// edges point to the proxies a proxy or ProxyGroup depends on
+	graph := make(map[string][]string)
+	names := make([]string, 0, len(dialerProxies))
+	for name, dialer := range dialerProxies {
+		graph[name] = append(graph[name], dialer)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, mapping := range groupsConfig {
+		option := &outboundgroup.GroupCommonOption{}
+		if err := decoder.Decode(mapping, option); err != nil {
+			return fmt.Errorf("ProxyGroup %s: %s", option.Name, err.Error())
+		}
+		graph[option.Name] = append(graph[option.Name], option.Proxies...)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for idx, elm := range path {
+				if elm == name {
+					loop := append(path[idx:], name)
+					return fmt.Errorf("Loop is detected in dialer-proxy: %s", strings.Join(loop, " -> "))
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, next := range graph[name] {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialerProxyLoopCheck(t *testing.T) {
+	groups := []map[string]interface{}{
+		{"name": "auto", "type": "url-test", "proxies": []string{"ss1", "ss2"}},
+		{"name": "select", "type": "select", "proxies": []string{"auto", "vmess"}},
+	}
+
+	assert.Nil(t, dialerProxyLoopCheck(map[string]string{"ss1": "vmess", "vmess": "DIRECT"}, groups))
+
+	err := dialerProxyLoopCheck(map[string]string{"ss1": "ss1"}, groups)
+	assert.EqualError(t, err, "Loop is detected in dialer-proxy: ss1 -> ss1")
+
+	err = dialerProxyLoopCheck(map[string]string{"vmess": "select"}, groups)
+	assert.EqualError(t, err, "Loop is detected in dialer-proxy: vmess -> select -> vmess")
+
+	err = dialerProxyLoopCheck(map[string]string{"ss1": "vmess", "vmess": "auto"}, groups)
+	assert.EqualError(t, err, "Loop is detected in dialer-proxy: ss1 -> vmess -> auto -> ss1")
+}
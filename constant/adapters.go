@@ -20,7 +20,12 @@ const (
 	Http
 	URLTest
 	Vmess
+	Vless
+	Trojan
 	LoadBalance
+	WireGuard
+	SSH
+	Relay
 )
 
 type ServerAdapter interface {
@@ -60,10 +65,16 @@ type PacketConn interface {
 type ProxyAdapter interface {
 	Name() string
 	Type() AdapterType
+	// Addr is the address of the proxy server, empty if the adapter has none
+	Addr() string
+	// StreamConn wraps c, a connection to the proxy server, into a connection to metadata
+	StreamConn(c net.Conn, metadata *Metadata) (net.Conn, error)
 	DialContext(ctx context.Context, metadata *Metadata) (Conn, error)
 	DialUDP(metadata *Metadata) (PacketConn, error)
 	SupportUDP() bool
 	MarshalJSON() ([]byte, error)
+	// Unwrap returns the proxy a group would use for metadata, nil if it is not a group
+	Unwrap(metadata *Metadata) Proxy
 }
 
 type DelayHistory struct {
@@ -107,8 +118,18 @@ func (at AdapterType) String() string {
 		return "URLTest"
 	case Vmess:
 		return "Vmess"
+	case Vless:
+		return "Vless"
+	case Trojan:
+		return "Trojan"
 	case LoadBalance:
 		return "LoadBalance"
+	case WireGuard:
+		return "WireGuard"
+	case SSH:
+		return "SSH"
+	case Relay:
+		return "Relay"
 	default:
 		return "Unknown"
 	}
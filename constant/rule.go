@@ -5,11 +5,22 @@ const (
 	Domain RuleType = iota
 	DomainSuffix
 	DomainKeyword
+	DomainRegex
+	DomainWildcard
 	GEOIP
+	GEOSITE
 	IPCIDR
 	SrcIPCIDR
 	SrcPort
 	DstPort
+	Process
+	ProcessPath
+	Network
+	InType
+	RuleSet
+	AND
+	OR
+	NOT
 	MATCH
 )
 
@@ -23,8 +34,14 @@ func (rt RuleType) String() string {
 		return "DomainSuffix"
 	case DomainKeyword:
 		return "DomainKeyword"
+	case DomainRegex:
+		return "DomainRegex"
+	case DomainWildcard:
+		return "DomainWildcard"
 	case GEOIP:
 		return "GeoIP"
+	case GEOSITE:
+		return "GEOSITE"
 	case IPCIDR:
 		return "IPCIDR"
 	case SrcIPCIDR:
@@ -33,6 +50,22 @@ func (rt RuleType) String() string {
 		return "SrcPort"
 	case DstPort:
 		return "DstPort"
+	case Process:
+		return "Process"
+	case ProcessPath:
+		return "ProcessPath"
+	case Network:
+		return "Network"
+	case InType:
+		return "InType"
+	case RuleSet:
+		return "RuleSet"
+	case AND:
+		return "AND"
+	case OR:
+		return "OR"
+	case NOT:
+		return "NOT"
 	case MATCH:
 		return "Match"
 	default:
@@ -46,4 +79,5 @@ type Rule interface {
 	Adapter() string
 	Payload() string
 	NoResolveIP() bool
+	ShouldFindProcess() bool
 }
@@ -24,6 +24,7 @@ require (
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
 	gopkg.in/eapache/channels.v1 v1.1.0
 	gopkg.in/yaml.v2 v2.2.8
+	lukechampine.com/blake3 v1.1.7
 )
 
 replace github.com/google/netstack => github.com/comzyh/netstack v0.0.0-20191217044024-67c27819ada4
@@ -2,12 +2,16 @@ package executor
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/outboundgroup"
 	"github.com/Dreamacro/clash/adapters/provider"
 	"github.com/Dreamacro/clash/component/auth"
+	"github.com/Dreamacro/clash/component/cachefile"
 	"github.com/Dreamacro/clash/component/dialer"
 	trie "github.com/Dreamacro/clash/component/domain-trie"
 	"github.com/Dreamacro/clash/component/resolver"
@@ -83,9 +87,10 @@ func ApplyConfig(cfg *config.Config, force bool) {
 		updateGeneral(cfg.General)
 	}
 	updateProxies(cfg.Proxies, cfg.Providers)
-	updateRules(cfg.Rules)
+	updateRules(cfg.Rules, cfg.RuleProviders)
 	updateHosts(cfg.Hosts)
 	updateExperimental(cfg)
+	restoreSelected(cfg.Proxies)
 }
 
 func GetGeneral() *config.General {
@@ -167,10 +172,59 @@ func updateProxies(proxies map[string]C.Proxy, providers map[string]provider.Pro
 	}
 
 	tunnel.UpdateProxies(proxies, providers)
+
+	// release what the old proxies hold, e.g. the netstack of wireguard
+	for _, provider := range oldProviders {
+		for _, proxy := range provider.Proxies() {
+			closeProxy(proxy)
+		}
+	}
+}
+
+func closeProxy(proxy C.Proxy) {
+	p, ok := proxy.(*outbound.Proxy)
+	if !ok {
+		return
+	}
+
+	if closer, ok := p.ProxyAdapter.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
-func updateRules(rules []C.Rule) {
-	tunnel.UpdateRules(rules)
+// restoreSelected restores the selections of the selectors, the mappings
+// of the groups or proxies which no longer exist are cleared
+func restoreSelected(proxies map[string]C.Proxy) {
+	store := cachefile.Cache()
+	selected := map[string]string{}
+	for group, name := range store.SelectedMap() {
+		proxy, exist := proxies[group]
+		if !exist {
+			continue
+		}
+
+		selector, ok := proxy.(*outbound.Proxy).ProxyAdapter.(*outboundgroup.Selector)
+		if !ok {
+			continue
+		}
+
+		if err := selector.Set(name); err != nil {
+			continue
+		}
+		selected[group] = name
+	}
+	store.SetSelectedMap(selected)
+}
+
+func updateRules(rules []C.Rule, ruleProviders map[string]provider.RuleProvider) {
+	oldProviders := tunnel.RuleProviders()
+
+	// close providers goroutine
+	for _, provider := range oldProviders {
+		provider.Destroy()
+	}
+
+	tunnel.UpdateRules(rules, ruleProviders)
 }
 
 func updateGeneral(general *config.General) {
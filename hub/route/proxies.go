@@ -9,6 +9,7 @@ import (
 
 	"github.com/Dreamacro/clash/adapters/outbound"
 	"github.com/Dreamacro/clash/adapters/outboundgroup"
+	"github.com/Dreamacro/clash/component/cachefile"
 	C "github.com/Dreamacro/clash/constant"
 	"github.com/Dreamacro/clash/tunnel"
 
@@ -91,6 +92,8 @@ func updateProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cachefile.Cache().SetSelected(proxy.Name(), req.Name)
+
 	render.NoContent(w, r)
 }
 
@@ -1,8 +1,13 @@
 package route
 
 import (
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	C "github.com/Dreamacro/clash/constant"
 	"github.com/Dreamacro/clash/tunnel"
 
 	"github.com/go-chi/chi"
@@ -12,13 +17,19 @@ import (
 func ruleRouter() http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getRules)
+	r.Delete("/stats", resetRuleStats)
+	r.Get("/match", matchRule)
 	return r
 }
 
 type Rule struct {
-	Type    string `json:"type"`
-	Payload string `json:"payload"`
-	Proxy   string `json:"proxy"`
+	Type          string     `json:"type"`
+	Payload       string     `json:"payload"`
+	Proxy         string     `json:"proxy"`
+	Hits          int64      `json:"hits"`
+	Upload        int64      `json:"upload"`
+	Download      int64      `json:"download"`
+	LastMatchedAt *time.Time `json:"lastMatchedAt"`
 }
 
 func getRules(w http.ResponseWriter, r *http.Request) {
@@ -26,14 +37,96 @@ func getRules(w http.ResponseWriter, r *http.Request) {
 
 	rules := []Rule{}
 	for _, rule := range rawRules {
-		rules = append(rules, Rule{
+		item := Rule{
 			Type:    rule.RuleType().String(),
 			Payload: rule.Payload(),
 			Proxy:   rule.Adapter(),
-		})
+		}
+
+		if statistic := tunnel.Statistic(rule); statistic != nil {
+			item.Hits = statistic.Hits()
+			item.Upload = statistic.UploadTotal()
+			item.Download = statistic.DownloadTotal()
+			item.LastMatchedAt = statistic.LastMatch()
+		}
+
+		rules = append(rules, item)
 	}
 
 	render.JSON(w, r, render.M{
 		"rules": rules,
 	})
 }
+
+func resetRuleStats(w http.ResponseWriter, r *http.Request) {
+	tunnel.ResetStatistics()
+	render.NoContent(w, r)
+}
+
+// parseMatchQuery builds the metadata of a connection from
+// host, port, network (tcp by default) and src (ip or ip:port)
+func parseMatchQuery(r *http.Request) (*C.Metadata, bool) {
+	query := r.URL.Query()
+	host, port := query.Get("host"), query.Get("port")
+	if host == "" {
+		return nil, false
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, false
+	}
+
+	metadata := &C.Metadata{DstPort: port}
+
+	switch strings.ToLower(query.Get("network")) {
+	case "", "tcp":
+		metadata.NetWork = C.TCP
+	case "udp":
+		metadata.NetWork = C.UDP
+	default:
+		return nil, false
+	}
+
+	if ip := net.ParseIP(host); ip == nil {
+		metadata.AddrType = C.AtypDomainName
+		metadata.Host = strings.ToLower(host)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		metadata.AddrType = C.AtypIPv4
+		metadata.DstIP = ip4
+	} else {
+		metadata.AddrType = C.AtypIPv6
+		metadata.DstIP = ip
+	}
+
+	if src := query.Get("src"); src != "" {
+		srcIP, srcPort := src, ""
+		if h, p, err := net.SplitHostPort(src); err == nil {
+			srcIP, srcPort = h, p
+		}
+
+		if metadata.SrcIP = net.ParseIP(srcIP); metadata.SrcIP == nil {
+			return nil, false
+		}
+		metadata.SrcPort = srcPort
+	}
+
+	return metadata, true
+}
+
+func matchRule(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := parseMatchQuery(r)
+	if !ok {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrBadRequest)
+		return
+	}
+
+	explanation, err := tunnel.Explain(metadata)
+	if err != nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+
+	render.JSON(w, r, explanation)
+}
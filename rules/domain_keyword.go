@@ -35,6 +35,10 @@ func (dk *DomainKeyword) NoResolveIP() bool {
 	return true
 }
 
+func (dk *DomainKeyword) ShouldFindProcess() bool {
+	return false
+}
+
 func NewDomainKeyword(keyword string, adapter string) *DomainKeyword {
 	return &DomainKeyword{
 		keyword: strings.ToLower(keyword),
@@ -0,0 +1,51 @@
+package rules
+
+import (
+	"regexp"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type DomainRegex struct {
+	regex   *regexp.Regexp
+	adapter string
+}
+
+func (dr *DomainRegex) RuleType() C.RuleType {
+	return C.DomainRegex
+}
+
+func (dr *DomainRegex) Match(metadata *C.Metadata) bool {
+	if metadata.AddrType != C.AtypDomainName {
+		return false
+	}
+	return dr.regex.MatchString(metadata.Host)
+}
+
+func (dr *DomainRegex) Adapter() string {
+	return dr.adapter
+}
+
+func (dr *DomainRegex) Payload() string {
+	return dr.regex.String()
+}
+
+func (dr *DomainRegex) NoResolveIP() bool {
+	return true
+}
+
+func (dr *DomainRegex) ShouldFindProcess() bool {
+	return false
+}
+
+func NewDomainRegex(regex string, adapter string) (*DomainRegex, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DomainRegex{
+		regex:   r,
+		adapter: adapter,
+	}, nil
+}
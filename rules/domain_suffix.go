@@ -35,6 +35,10 @@ func (ds *DomainSuffix) NoResolveIP() bool {
 	return true
 }
 
+func (ds *DomainSuffix) ShouldFindProcess() bool {
+	return false
+}
+
 func NewDomainSuffix(suffix string, adapter string) *DomainSuffix {
 	return &DomainSuffix{
 		suffix:  strings.ToLower(suffix),
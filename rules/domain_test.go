@@ -0,0 +1,39 @@
+package rules
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainRegex(t *testing.T) {
+	rule, err := NewDomainRegex(`^ads?\d*\.example\.(com|net)$`, "REJECT")
+	assert.Nil(t, err)
+	assert.Equal(t, C.DomainRegex, rule.RuleType())
+	assert.True(t, rule.Match(domainMetadata("ads12.example.com")))
+	assert.True(t, rule.Match(domainMetadata("ad.example.net")))
+	assert.False(t, rule.Match(domainMetadata("badge.example.com")))
+	assert.False(t, rule.Match(domainMetadata("ads.example.org")))
+	assert.False(t, rule.Match(&C.Metadata{AddrType: C.AtypIPv4}))
+
+	_, err = NewDomainRegex(`(unclosed`, "REJECT")
+	assert.NotNil(t, err)
+}
+
+func TestDomainWildcard(t *testing.T) {
+	rule, err := NewDomainWildcard("*.cdn-??.Example.com", "DIRECT")
+	assert.Nil(t, err)
+	assert.Equal(t, "*.cdn-??.example.com", rule.Payload())
+	assert.True(t, rule.Match(domainMetadata("img.cdn-01.example.com")))
+	assert.True(t, rule.Match(domainMetadata("a.b.cdn-ab.example.com")))
+	assert.False(t, rule.Match(domainMetadata("img.cdn-001.example.com")))
+	assert.False(t, rule.Match(domainMetadata("cdn-01.example.com")))
+	assert.False(t, rule.Match(domainMetadata("img.cdn-01xexample.com")))
+
+	for _, pattern := range []string{"", "a_b.com", "exa(mple).com"} {
+		_, err := NewDomainWildcard(pattern, "DIRECT")
+		assert.NotNil(t, err, pattern)
+	}
+}
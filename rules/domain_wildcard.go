@@ -0,0 +1,76 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type DomainWildcard struct {
+	pattern string
+	regex   *regexp.Regexp
+	adapter string
+}
+
+func (dw *DomainWildcard) RuleType() C.RuleType {
+	return C.DomainWildcard
+}
+
+func (dw *DomainWildcard) Match(metadata *C.Metadata) bool {
+	if metadata.AddrType != C.AtypDomainName {
+		return false
+	}
+	return dw.regex.MatchString(metadata.Host)
+}
+
+func (dw *DomainWildcard) Adapter() string {
+	return dw.adapter
+}
+
+func (dw *DomainWildcard) Payload() string {
+	return dw.pattern
+}
+
+func (dw *DomainWildcard) NoResolveIP() bool {
+	return true
+}
+
+func (dw *DomainWildcard) ShouldFindProcess() bool {
+	return false
+}
+
+// NewDomainWildcard builds a rule matching domains against pattern,
+// `*` matches any sequence of characters and `?` matches exactly one
+func NewDomainWildcard(pattern string, adapter string) (*DomainWildcard, error) {
+	pattern = strings.ToLower(pattern)
+	if pattern == "" {
+		return nil, errors.New("empty wildcard pattern")
+	}
+
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for _, c := range pattern {
+		switch {
+		case c == '*':
+			expr.WriteString(".*")
+		case c == '?':
+			expr.WriteString(".")
+		case c == '.':
+			expr.WriteString(`\.`)
+		case c == '-', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			expr.WriteRune(c)
+		default:
+			return nil, fmt.Errorf("invalid character %q in wildcard pattern %s", c, pattern)
+		}
+	}
+	expr.WriteString("$")
+
+	return &DomainWildcard{
+		pattern: pattern,
+		regex:   regexp.MustCompile(expr.String()),
+		adapter: adapter,
+	}, nil
+}
@@ -28,6 +28,10 @@ func (f *Match) NoResolveIP() bool {
 	return true
 }
 
+func (f *Match) ShouldFindProcess() bool {
+	return false
+}
+
 func NewMatch(adapter string) *Match {
 	return &Match{
 		adapter: adapter,
@@ -36,6 +36,10 @@ func (g *GEOIP) NoResolveIP() bool {
 	return g.noResolveIP
 }
 
+func (g *GEOIP) ShouldFindProcess() bool {
+	return false
+}
+
 func NewGEOIP(country string, adapter string, noResolveIP bool) *GEOIP {
 	geoip := &GEOIP{
 		country:     country,
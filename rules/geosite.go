@@ -0,0 +1,52 @@
+package rules
+
+import (
+	"github.com/Dreamacro/clash/component/geosite"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type GEOSITE struct {
+	category string
+	adapter  string
+	matcher  *geosite.Matcher
+}
+
+func (gs *GEOSITE) RuleType() C.RuleType {
+	return C.GEOSITE
+}
+
+func (gs *GEOSITE) Match(metadata *C.Metadata) bool {
+	if metadata.AddrType != C.AtypDomainName {
+		return false
+	}
+	return gs.matcher.Match(metadata.Host)
+}
+
+func (gs *GEOSITE) Adapter() string {
+	return gs.adapter
+}
+
+func (gs *GEOSITE) Payload() string {
+	return gs.category
+}
+
+func (gs *GEOSITE) NoResolveIP() bool {
+	return true
+}
+
+func (gs *GEOSITE) ShouldFindProcess() bool {
+	return false
+}
+
+func NewGEOSITE(category string, adapter string) (*GEOSITE, error) {
+	matcher, err := geosite.Load(C.Path.GeoSite(), category)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GEOSITE{
+		category: category,
+		adapter:  adapter,
+		matcher:  matcher,
+	}, nil
+}
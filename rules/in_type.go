@@ -0,0 +1,63 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type InType struct {
+	tp      C.Type
+	payload string
+	adapter string
+}
+
+func (i *InType) RuleType() C.RuleType {
+	return C.InType
+}
+
+func (i *InType) Match(metadata *C.Metadata) bool {
+	return metadata.Type == i.tp
+}
+
+func (i *InType) Adapter() string {
+	return i.adapter
+}
+
+func (i *InType) Payload() string {
+	return i.payload
+}
+
+func (i *InType) NoResolveIP() bool {
+	return true
+}
+
+func (i *InType) ShouldFindProcess() bool {
+	return false
+}
+
+func NewInType(tp string, adapter string) (*InType, error) {
+	payload := strings.ToUpper(tp)
+	it := &InType{
+		payload: payload,
+		adapter: adapter,
+	}
+
+	switch payload {
+	case "HTTP":
+		it.tp = C.HTTP
+	case "HTTPCONNECT", "HTTP-CONNECT":
+		it.tp = C.HTTPCONNECT
+	case "SOCKS", "SOCKS5":
+		it.tp = C.SOCKS
+	case "REDIR":
+		it.tp = C.REDIR
+	case "TUN":
+		it.tp = C.TUN
+	default:
+		return nil, fmt.Errorf("unsupported inbound type %s", tp)
+	}
+
+	return it, nil
+}
@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInType(t *testing.T) {
+	types := []C.Type{C.HTTP, C.HTTPCONNECT, C.SOCKS, C.REDIR, C.TUN}
+	cases := []struct {
+		payload string
+		tp      C.Type
+	}{
+		{"HTTP", C.HTTP},
+		{"http", C.HTTP},
+		{"HTTPCONNECT", C.HTTPCONNECT},
+		{"http-connect", C.HTTPCONNECT},
+		{"SOCKS", C.SOCKS},
+		{"socks5", C.SOCKS},
+		{"REDIR", C.REDIR},
+		{"tun", C.TUN},
+	}
+
+	for _, c := range cases {
+		rule, err := NewInType(c.payload, "DIRECT")
+		if !assert.Nil(t, err, c.payload) {
+			continue
+		}
+
+		for _, tp := range types {
+			assert.Equal(t, tp == c.tp, rule.Match(&C.Metadata{Type: tp}), "%s %s", c.payload, tp)
+		}
+	}
+
+	for _, payload := range []string{"", "socks4", "mixed", "HTTP,SOCKS"} {
+		_, err := NewInType(payload, "DIRECT")
+		assert.NotNil(t, err, payload)
+	}
+}
+
+func TestInType_Parse(t *testing.T) {
+	rule, err := ParseRule("IN-TYPE", "socks5", "PROXY", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, C.InType, rule.RuleType())
+	assert.Equal(t, "SOCKS5", rule.Payload())
+	assert.Equal(t, "PROXY", rule.Adapter())
+	assert.True(t, rule.Match(&C.Metadata{Type: C.SOCKS}))
+	assert.False(t, rule.Match(&C.Metadata{Type: C.TUN}))
+}
@@ -0,0 +1,235 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	cidr "github.com/Dreamacro/clash/component/cidr-trie"
+	trie "github.com/Dreamacro/clash/component/domain-trie"
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// runs shorter than this are cheaper to scan than to look up
+const minIndexedRun = 4
+
+type block interface {
+	// match returns the position of the first rule in [from, end) matching metadata
+	match(metadata *C.Metadata, from int) (int, bool)
+	end() int
+}
+
+// first returns the first position in the ascending list that is not less than from
+func first(positions []int, from int) (int, bool) {
+	i := sort.SearchInts(positions, from)
+	if i == len(positions) {
+		return 0, false
+	}
+	return positions[i], true
+}
+
+type domainEntry struct {
+	exact  []int
+	suffix []int
+}
+
+// domainBlock is a run of consecutive DOMAIN and DOMAIN-SUFFIX rules
+type domainBlock struct {
+	tree *trie.Trie
+	last int
+}
+
+func (db *domainBlock) insert(domain string, idx int, suffix bool) {
+	var entry *domainEntry
+	if node := db.tree.Search(domain); node != nil {
+		entry = node.Data.(*domainEntry)
+	} else {
+		entry = &domainEntry{}
+		db.tree.Insert(domain, entry)
+	}
+
+	if suffix {
+		entry.suffix = append(entry.suffix, idx)
+	} else {
+		entry.exact = append(entry.exact, idx)
+	}
+	db.last = idx
+}
+
+func (db *domainBlock) match(metadata *C.Metadata, from int) (int, bool) {
+	if metadata.AddrType != C.AtypDomainName {
+		return 0, false
+	}
+
+	matched, found := 0, false
+	update := func(positions []int) {
+		if idx, ok := first(positions, from); ok && (!found || idx < matched) {
+			matched, found = idx, true
+		}
+	}
+
+	db.tree.Walk(metadata.Host, func(data interface{}, exact bool) {
+		entry := data.(*domainEntry)
+		update(entry.suffix)
+		if exact {
+			update(entry.exact)
+		}
+	})
+
+	return matched, found
+}
+
+func (db *domainBlock) end() int {
+	return db.last + 1
+}
+
+// ipcidrBlock is a run of consecutive IP-CIDR rules sharing the same no-resolve option
+type ipcidrBlock struct {
+	tree *cidr.Trie
+	last int
+}
+
+func (ib *ipcidrBlock) insert(rule *IPCIDR, idx int) {
+	positions, _ := ib.tree.Get(rule.ipnet).(*[]int)
+	if positions == nil {
+		positions = &[]int{}
+		ib.tree.Insert(rule.ipnet, positions)
+	}
+
+	*positions = append(*positions, idx)
+	ib.last = idx
+}
+
+func (ib *ipcidrBlock) match(metadata *C.Metadata, from int) (int, bool) {
+	if metadata.DstIP == nil {
+		return 0, false
+	}
+
+	matched, found := 0, false
+	ib.tree.Walk(metadata.DstIP, func(data interface{}) {
+		if idx, ok := first(*data.(*[]int), from); ok && (!found || idx < matched) {
+			matched, found = idx, true
+		}
+	})
+
+	return matched, found
+}
+
+func (ib *ipcidrBlock) end() int {
+	return ib.last + 1
+}
+
+// isIndexableDomain reports whether domain is stored in the trie as it is,
+// wildcard characters would be expanded by the trie so they are left out
+func isIndexableDomain(domain string) bool {
+	return domain != "" && domain[0] != '.' && domain[len(domain)-1] != '.' &&
+		!strings.ContainsAny(domain, "*+")
+}
+
+// Index speeds up finding the first matching rule by grouping runs of
+// consecutive DOMAIN / DOMAIN-SUFFIX rules and runs of IP-CIDR rules into
+// lookup trees, the rule order is kept as is.
+type Index struct {
+	rules  []C.Rule
+	blocks []block
+}
+
+// Match returns the position of the first rule from idx on that matches metadata.
+// If idx is inside an indexed run, only the rest of the run is checked, and
+// the last position of the run is returned when nothing matched.
+func (i *Index) Match(idx int, metadata *C.Metadata) (int, bool) {
+	b := i.blocks[idx]
+	if b == nil {
+		return idx, i.rules[idx].Match(metadata)
+	}
+
+	if matched, ok := b.match(metadata, idx); ok {
+		return matched, true
+	}
+	return b.end() - 1, false
+}
+
+// NewIndex builds an Index over rules
+func NewIndex(rules []C.Rule) *Index {
+	index := &Index{
+		rules:  rules,
+		blocks: make([]block, len(rules)),
+	}
+
+	for start := 0; start < len(rules); {
+		end := start
+		for end < len(rules) && sameKind(rules[start], rules[end]) {
+			end++
+		}
+
+		if end-start >= minIndexedRun {
+			b := newBlock(rules, start, end)
+			for idx := start; idx < end; idx++ {
+				index.blocks[idx] = b
+			}
+		}
+
+		if end == start {
+			end++
+		}
+		start = end
+	}
+
+	return index
+}
+
+type ruleKind int
+
+const (
+	kindNone ruleKind = iota
+	kindDomain
+	kindIPCIDR
+	kindIPCIDRNoResolve
+)
+
+func kindOf(rule C.Rule) ruleKind {
+	switch r := rule.(type) {
+	case *Domain:
+		if isIndexableDomain(r.domain) {
+			return kindDomain
+		}
+	case *DomainSuffix:
+		if isIndexableDomain(r.suffix) {
+			return kindDomain
+		}
+	case *IPCIDR:
+		if r.isSourceIP {
+			return kindNone
+		}
+		if r.noResolveIP {
+			return kindIPCIDRNoResolve
+		}
+		return kindIPCIDR
+	}
+	return kindNone
+}
+
+func sameKind(a, b C.Rule) bool {
+	kind := kindOf(a)
+	return kind != kindNone && kind == kindOf(b)
+}
+
+func newBlock(rules []C.Rule, start, end int) block {
+	if kindOf(rules[start]) == kindDomain {
+		b := &domainBlock{tree: trie.New()}
+		for idx := start; idx < end; idx++ {
+			switch r := rules[idx].(type) {
+			case *Domain:
+				b.insert(r.domain, idx, false)
+			case *DomainSuffix:
+				b.insert(r.suffix, idx, true)
+			}
+		}
+		return b
+	}
+
+	b := &ipcidrBlock{tree: cidr.New()}
+	for idx := start; idx < end; idx++ {
+		b.insert(rules[idx].(*IPCIDR), idx)
+	}
+	return b
+}
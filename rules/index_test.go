@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+func linearMatch(rules []C.Rule, metadata *C.Metadata) (int, bool) {
+	for idx, rule := range rules {
+		if rule.Match(metadata) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+func indexMatch(index *Index, rules []C.Rule, metadata *C.Metadata, from int) (int, bool) {
+	for idx := from; idx < len(rules); idx++ {
+		var matched bool
+		if idx, matched = index.Match(idx, metadata); matched {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+func buildRules(count int) []C.Rule {
+	rules := []C.Rule{}
+	for i := 0; i < count; i++ {
+		rules = append(rules, NewDomainSuffix(fmt.Sprintf("domain%d.com", i), "DIRECT"))
+	}
+	for i := 0; i < count; i++ {
+		ipcidr, _ := NewIPCIDR(fmt.Sprintf("10.%d.%d.0/24", i/256%256, i%256), "DIRECT", WithIPCIDRNoResolve(true))
+		rules = append(rules, ipcidr)
+	}
+	return append(rules, NewMatch("DIRECT"))
+}
+
+func domainMetadata(host string) *C.Metadata {
+	return &C.Metadata{AddrType: C.AtypDomainName, Host: host}
+}
+
+func ipMetadata(ip string) *C.Metadata {
+	return &C.Metadata{AddrType: C.AtypIPv4, DstIP: net.ParseIP(ip)}
+}
+
+func TestIndex_FirstMatch(t *testing.T) {
+	ipcidr, _ := NewIPCIDR("10.0.0.0/8", "10/8")
+	ipcidr16, _ := NewIPCIDR("10.1.0.0/16", "10.1/16")
+	ipcidrDup, _ := NewIPCIDR("10.0.0.0/8", "10/8-dup")
+	ipcidrV6, _ := NewIPCIDR("2001:db8::/32", "v6")
+	srcIPCIDR, _ := NewIPCIDR("10.0.0.0/8", "src", WithIPCIDRSourceIP(true))
+	port, _ := NewPort("443", "port", false)
+
+	rules := []C.Rule{
+		NewDomain("www.google.com", "exact"),
+		NewDomainSuffix("google.com", "suffix"),
+		NewDomainSuffix("www.google.com", "suffix-www"),
+		NewDomain("google.com", "exact-root"),
+		NewDomainSuffix("com", "com"),
+		NewDomainKeyword("google", "keyword"),
+		NewDomain("*.wildcard.com", "wildcard"),
+		ipcidr16,
+		ipcidr,
+		ipcidrDup,
+		ipcidrV6,
+		port,
+		srcIPCIDR,
+		NewMatch("match"),
+	}
+
+	cases := []*C.Metadata{
+		domainMetadata("www.google.com"),
+		domainMetadata("google.com"),
+		domainMetadata("mail.google.com"),
+		domainMetadata("example.com"),
+		domainMetadata("google.org"),
+		domainMetadata("a.wildcard.org"),
+		ipMetadata("10.1.2.3"),
+		ipMetadata("10.2.2.3"),
+		ipMetadata("11.2.2.3"),
+		{AddrType: C.AtypIPv6, DstIP: net.ParseIP("2001:db8::1")},
+		{AddrType: C.AtypIPv4, DstIP: net.ParseIP("1.1.1.1"), DstPort: "443"},
+	}
+
+	index := NewIndex(rules)
+	for _, metadata := range cases {
+		// resuming after a skipped rule must find the next matching one
+		for from := 0; from < len(rules); from++ {
+			expected, expectedOk := linearMatch(rules[from:], metadata)
+			actual, actualOk := indexMatch(index, rules, metadata, from)
+			if expectedOk != actualOk || (expectedOk && expected+from != actual) {
+				t.Errorf("%s from %d: expected rule %d, got %d", metadata.String(), from, expected+from, actual)
+			}
+		}
+	}
+}
+
+func benchmarkMatch(b *testing.B, metadata *C.Metadata, indexed bool) {
+	rules := buildRules(10000)
+	index := NewIndex(rules)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if indexed {
+			indexMatch(index, rules, metadata, 0)
+		} else {
+			linearMatch(rules, metadata)
+		}
+	}
+}
+
+func BenchmarkLinear_DomainMiss(b *testing.B) {
+	benchmarkMatch(b, domainMetadata("www.example.org"), false)
+}
+
+func BenchmarkIndex_DomainMiss(b *testing.B) {
+	benchmarkMatch(b, domainMetadata("www.example.org"), true)
+}
+
+func BenchmarkLinear_DomainHit(b *testing.B) {
+	benchmarkMatch(b, domainMetadata("www.domain9999.com"), false)
+}
+
+func BenchmarkIndex_DomainHit(b *testing.B) {
+	benchmarkMatch(b, domainMetadata("www.domain9999.com"), true)
+}
+
+func BenchmarkLinear_IPHit(b *testing.B) {
+	benchmarkMatch(b, ipMetadata("10.39.15.1"), false)
+}
+
+func BenchmarkIndex_IPHit(b *testing.B) {
+	benchmarkMatch(b, ipMetadata("10.39.15.1"), true)
+}
@@ -0,0 +1,196 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+var (
+	errUnbalancedParen = errors.New("unbalanced parentheses")
+	errLogicPayload    = errors.New("payload must be like ((TYPE,PAYLOAD),(TYPE,PAYLOAD))")
+)
+
+// SubRuleParser builds a sub-rule of a logic rule from its type, payload and params
+type SubRuleParser = func(tp, payload string, params []string) (C.Rule, error)
+
+type Logic struct {
+	tp          C.RuleType
+	payload     string
+	adapter     string
+	rules       []C.Rule
+	noResolveIP bool
+	findProcess bool
+}
+
+func (l *Logic) RuleType() C.RuleType {
+	return l.tp
+}
+
+func (l *Logic) Match(metadata *C.Metadata) bool {
+	switch l.tp {
+	case C.AND:
+		for _, rule := range l.rules {
+			if !rule.Match(metadata) {
+				return false
+			}
+		}
+		return true
+	case C.OR:
+		for _, rule := range l.rules {
+			if rule.Match(metadata) {
+				return true
+			}
+		}
+		return false
+	default:
+		return !l.rules[0].Match(metadata)
+	}
+}
+
+func (l *Logic) Adapter() string {
+	return l.adapter
+}
+
+func (l *Logic) Payload() string {
+	return l.payload
+}
+
+func (l *Logic) NoResolveIP() bool {
+	return l.noResolveIP
+}
+
+func (l *Logic) ShouldFindProcess() bool {
+	return l.findProcess
+}
+
+// IsLogic reports whether tp is the type of a logic rule
+func IsLogic(tp string) bool {
+	return tp == "AND" || tp == "OR" || tp == "NOT"
+}
+
+// SplitRule splits a rule line by the commas that are not inside parentheses
+func SplitRule(line string) ([]string, error) {
+	result := []string{}
+	depth, start := 0, 0
+	for i, c := range line {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errUnbalancedParen
+			}
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, errUnbalancedParen
+	}
+
+	return append(result, strings.TrimSpace(line[start:])), nil
+}
+
+// trimParen removes one pair of parentheses around s
+func trimParen(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return "", false
+	}
+	return strings.TrimSpace(s[1 : len(s)-1]), true
+}
+
+func parseSubRules(payload string, parse SubRuleParser) ([]C.Rule, error) {
+	inner, ok := trimParen(payload)
+	if !ok {
+		return nil, errLogicPayload
+	}
+
+	items, err := SplitRule(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []C.Rule{}
+	for _, item := range items {
+		content, ok := trimParen(item)
+		if !ok {
+			return nil, errLogicPayload
+		}
+
+		rule, err := SplitRuleLine(content, false)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			payload string
+			params  = []string{}
+		)
+		if len(rule) >= 2 {
+			payload = rule[1]
+			params = rule[2:]
+		}
+
+		parsed, err := parse(rule[0], payload, params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item, err)
+		}
+		rules = append(rules, parsed)
+	}
+
+	return rules, nil
+}
+
+// NewLogic builds an AND, OR or NOT rule, sub-rules are parsed by parse
+// so the caller decides which rule types may be nested
+func NewLogic(tp, payload, adapter string, parse SubRuleParser) (*Logic, error) {
+	rules, err := parseSubRules(payload, parse)
+	if err != nil {
+		return nil, err
+	}
+
+	logic := &Logic{
+		payload:     payload,
+		adapter:     adapter,
+		rules:       rules,
+		noResolveIP: true,
+	}
+
+	switch tp {
+	case "AND":
+		logic.tp = C.AND
+	case "OR":
+		logic.tp = C.OR
+	case "NOT":
+		logic.tp = C.NOT
+	default:
+		return nil, fmt.Errorf("unsupported rule type %s", tp)
+	}
+
+	if logic.tp == C.NOT && len(rules) != 1 {
+		return nil, errors.New("NOT rule must have exactly one sub-rule")
+	} else if len(rules) == 0 {
+		return nil, fmt.Errorf("%s rule must have at least one sub-rule", tp)
+	}
+
+	for _, rule := range rules {
+		// the destination has to be resolved before matching
+		// if any of the sub-rules depends on it
+		if !rule.NoResolveIP() {
+			logic.noResolveIP = false
+		}
+		if rule.ShouldFindProcess() {
+			logic.findProcess = true
+		}
+	}
+
+	return logic, nil
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"net"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseLogic(line string) (C.Rule, error) {
+	rule, err := SplitRule(line)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRule(rule[0], rule[1], rule[2], rule[3:])
+}
+
+func TestSplitRule(t *testing.T) {
+	rule, err := SplitRule("AND, ((DOMAIN,a.com),(DST-PORT,443)), REJECT")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AND", "((DOMAIN,a.com),(DST-PORT,443))", "REJECT"}, rule)
+
+	_, err = SplitRule("AND,((DOMAIN,a.com),REJECT")
+	assert.NotNil(t, err)
+
+	_, err = SplitRule("AND,(DOMAIN,a.com)),REJECT")
+	assert.NotNil(t, err)
+}
+
+func TestSplitRuleLine(t *testing.T) {
+	rule, err := SplitRuleLine("DOMAIN-REGEX, ^a{1,3}\\.com$ ,REJECT", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DOMAIN-REGEX", "^a{1,3}\\.com$", "REJECT"}, rule)
+
+	rule, err = SplitRuleLine("DOMAIN-REGEX,^a{1,3}\\.com$", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DOMAIN-REGEX", "^a{1,3}\\.com$"}, rule)
+
+	rule, err = SplitRuleLine("IP-CIDR, 10.0.0.0/8, DIRECT, no-resolve", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"IP-CIDR", "10.0.0.0/8", "DIRECT", "no-resolve"}, rule)
+
+	rule, err = SplitRuleLine("AND,((DOMAIN,a.com),(DST-PORT,443)),REJECT", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AND", "((DOMAIN,a.com),(DST-PORT,443))", "REJECT"}, rule)
+}
+
+func TestLogic_Match(t *testing.T) {
+	metadata := &C.Metadata{
+		AddrType: C.AtypDomainName,
+		Host:     "www.google.com",
+		DstPort:  "443",
+	}
+
+	and, err := parseLogic("AND,((DOMAIN-SUFFIX,google.com),(DST-PORT,443)),REJECT")
+	assert.Nil(t, err)
+	assert.Equal(t, C.AND, and.RuleType())
+	assert.Equal(t, "REJECT", and.Adapter())
+	assert.True(t, and.Match(metadata))
+
+	and, err = parseLogic("AND,((DOMAIN-SUFFIX,google.com),(DST-PORT,80)),REJECT")
+	assert.Nil(t, err)
+	assert.False(t, and.Match(metadata))
+
+	or, err := parseLogic("OR,((DOMAIN,a.com),(DST-PORT,443)),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, or.Match(metadata))
+
+	not, err := parseLogic("NOT,((DOMAIN-SUFFIX,google.com)),REJECT")
+	assert.Nil(t, err)
+	assert.False(t, not.Match(metadata))
+
+	regex, err := parseLogic("AND,((DOMAIN-REGEX,^www\\.[a-z]{1,10}\\.com$),(DST-PORT,443)),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, regex.Match(metadata))
+
+	nested, err := parseLogic("AND,((NOT,((DOMAIN,a.com))),(OR,((DST-PORT,80),(DST-PORT,443)))),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, nested.Match(metadata))
+}
+
+func TestLogic_NoResolveIP(t *testing.T) {
+	rule, err := parseLogic("AND,((DOMAIN,a.com),(DST-PORT,443)),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, rule.NoResolveIP())
+
+	rule, err = parseLogic("AND,((DOMAIN,a.com),(IP-CIDR,10.0.0.0/8)),REJECT")
+	assert.Nil(t, err)
+	assert.False(t, rule.NoResolveIP())
+
+	rule, err = parseLogic("AND,((DOMAIN,a.com),(IP-CIDR,10.0.0.0/8,no-resolve)),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, rule.NoResolveIP())
+
+	rule, err = parseLogic("OR,((DOMAIN,a.com),(NOT,((GEOIP,CN)))),REJECT")
+	assert.Nil(t, err)
+	assert.False(t, rule.NoResolveIP())
+
+	rule, err = parseLogic("NOT,((IP-CIDR,10.0.0.0/8)),REJECT")
+	assert.Nil(t, err)
+	assert.True(t, rule.Match(&C.Metadata{DstIP: net.ParseIP("1.1.1.1")}))
+}
+
+func TestLogic_Invalid(t *testing.T) {
+	invalid := []string{
+		"AND,(DOMAIN,a.com),REJECT",
+		"AND,(),REJECT",
+		"NOT,((DOMAIN,a.com),(DOMAIN,b.com)),REJECT",
+		"OR,((UNKNOWN,a.com)),REJECT",
+		"AND,((DOMAIN,a.com),DOMAIN),REJECT",
+	}
+
+	for _, line := range invalid {
+		_, err := parseLogic(line)
+		assert.NotNil(t, err, line)
+	}
+}
@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type NetworkType struct {
+	network C.NetWork
+	adapter string
+}
+
+func (n *NetworkType) RuleType() C.RuleType {
+	return C.Network
+}
+
+func (n *NetworkType) Match(metadata *C.Metadata) bool {
+	return metadata.NetWork == n.network
+}
+
+func (n *NetworkType) Adapter() string {
+	return n.adapter
+}
+
+func (n *NetworkType) Payload() string {
+	return n.network.String()
+}
+
+func (n *NetworkType) NoResolveIP() bool {
+	return true
+}
+
+func (n *NetworkType) ShouldFindProcess() bool {
+	return false
+}
+
+func NewNetworkType(network string, adapter string) (*NetworkType, error) {
+	nt := &NetworkType{
+		adapter: adapter,
+	}
+
+	switch strings.ToUpper(network) {
+	case "TCP":
+		nt.network = C.TCP
+	case "UDP":
+		nt.network = C.UDP
+	default:
+		return nil, fmt.Errorf("unsupported network type %s, only TCP or UDP", network)
+	}
+
+	return nt, nil
+}
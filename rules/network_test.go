@@ -0,0 +1,47 @@
+package rules
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkType(t *testing.T) {
+	for _, network := range []string{"tcp", "TCP", "udp", "Udp"} {
+		rule, err := NewNetworkType(network, "DIRECT")
+		if !assert.Nil(t, err, network) {
+			continue
+		}
+
+		tcp := &C.Metadata{NetWork: C.TCP}
+		udp := &C.Metadata{NetWork: C.UDP}
+		isTCP := network == "tcp" || network == "TCP"
+		assert.Equal(t, isTCP, rule.Match(tcp), network)
+		assert.Equal(t, !isTCP, rule.Match(udp), network)
+		assert.Equal(t, C.Network, rule.RuleType())
+		assert.True(t, rule.NoResolveIP())
+	}
+
+	for _, network := range []string{"", "icmp", "tcp6"} {
+		_, err := NewNetworkType(network, "DIRECT")
+		assert.NotNil(t, err, network)
+	}
+}
+
+func TestLogic_Network(t *testing.T) {
+	rule, err := parseLogic("AND,((DOMAIN-SUFFIX,google.com),(DST-PORT,443),(NETWORK,udp)),REJECT")
+	assert.Nil(t, err)
+
+	metadata := &C.Metadata{
+		NetWork:  C.UDP,
+		AddrType: C.AtypDomainName,
+		Host:     "www.google.com",
+		DstPort:  "443",
+	}
+	assert.True(t, rule.Match(metadata))
+
+	metadata.NetWork = C.TCP
+	assert.False(t, rule.Match(metadata))
+}
@@ -0,0 +1,96 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// ParseRule build a rule from its type, payload, target adapter and extra params
+func ParseRule(tp, payload, target string, params []string) (C.Rule, error) {
+	var (
+		parseErr error
+		parsed   C.Rule
+	)
+
+	switch tp {
+	case "DOMAIN":
+		parsed = NewDomain(payload, target)
+	case "DOMAIN-SUFFIX":
+		parsed = NewDomainSuffix(payload, target)
+	case "DOMAIN-KEYWORD":
+		parsed = NewDomainKeyword(payload, target)
+	case "DOMAIN-REGEX":
+		parsed, parseErr = NewDomainRegex(payload, target)
+	case "DOMAIN-WILDCARD":
+		parsed, parseErr = NewDomainWildcard(payload, target)
+	case "GEOIP":
+		noResolve := HasNoResolve(params)
+		parsed = NewGEOIP(payload, target, noResolve)
+	case "GEOSITE":
+		parsed, parseErr = NewGEOSITE(payload, target)
+	case "IP-CIDR", "IP-CIDR6":
+		noResolve := HasNoResolve(params)
+		parsed, parseErr = NewIPCIDR(payload, target, WithIPCIDRNoResolve(noResolve))
+	// deprecated when bump to 1.0
+	case "SOURCE-IP-CIDR":
+		fallthrough
+	case "SRC-IP-CIDR":
+		parsed, parseErr = NewIPCIDR(payload, target, WithIPCIDRSourceIP(true), WithIPCIDRNoResolve(true))
+	case "SRC-PORT":
+		parsed, parseErr = NewPort(payload, target, true)
+	case "DST-PORT":
+		parsed, parseErr = NewPort(payload, target, false)
+	case "PROCESS-NAME":
+		parsed = NewProcess(payload, target, true)
+	case "PROCESS-PATH":
+		parsed = NewProcess(payload, target, false)
+	case "NETWORK":
+		parsed, parseErr = NewNetworkType(payload, target)
+	case "IN-TYPE":
+		parsed, parseErr = NewInType(payload, target)
+	case "AND", "OR", "NOT":
+		parsed, parseErr = NewLogic(tp, payload, target, func(tp, payload string, params []string) (C.Rule, error) {
+			return ParseRule(tp, payload, "", params)
+		})
+	case "MATCH":
+		fallthrough
+	// deprecated when bump to 1.0
+	case "FINAL":
+		parsed = NewMatch(target)
+	default:
+		parseErr = fmt.Errorf("unsupported rule type %s", tp)
+	}
+
+	return parsed, parseErr
+}
+
+// SplitRuleLine splits a rule line into its fields. Logic rules are split by
+// the commas outside parentheses. The pattern of DOMAIN-REGEX may contain
+// commas, it ends at the last comma when the line has a target, otherwise it
+// is the rest of the line.
+func SplitRuleLine(line string, hasTarget bool) ([]string, error) {
+	first := strings.Index(line, ",")
+	if first == -1 {
+		return []string{strings.TrimSpace(line)}, nil
+	}
+
+	tp := strings.TrimSpace(line[:first])
+	switch {
+	case IsLogic(tp):
+		return SplitRule(line)
+	case tp == "DOMAIN-REGEX":
+		last := strings.LastIndex(line, ",")
+		if !hasTarget || last == first {
+			return []string{tp, strings.TrimSpace(line[first+1:])}, nil
+		}
+		return []string{tp, strings.TrimSpace(line[first+1 : last]), strings.TrimSpace(line[last+1:])}, nil
+	}
+
+	rule := strings.Split(line, ",")
+	for i := range rule {
+		rule[i] = strings.TrimSpace(rule[i])
+	}
+	return rule, nil
+}
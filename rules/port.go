@@ -1,15 +1,24 @@
 package rules
 
 import (
+	"sort"
 	"strconv"
+	"strings"
 
 	C "github.com/Dreamacro/clash/constant"
 )
 
+type portRange struct {
+	start int
+	end   int
+}
+
 type Port struct {
 	adapter  string
 	port     string
 	isSource bool
+	// sorted and non-overlapping
+	ranges []portRange
 }
 
 func (p *Port) RuleType() C.RuleType {
@@ -21,9 +30,21 @@ func (p *Port) RuleType() C.RuleType {
 
 func (p *Port) Match(metadata *C.Metadata) bool {
 	if p.isSource {
-		return metadata.SrcPort == p.port
+		return p.matchPort(metadata.SrcPort)
 	}
-	return metadata.DstPort == p.port
+	return p.matchPort(metadata.DstPort)
+}
+
+func (p *Port) matchPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+
+	i := sort.Search(len(p.ranges), func(i int) bool {
+		return p.ranges[i].end >= n
+	})
+	return i < len(p.ranges) && p.ranges[i].start <= n
 }
 
 func (p *Port) Adapter() string {
@@ -38,14 +59,66 @@ func (p *Port) NoResolveIP() bool {
 	return true
 }
 
+func (p *Port) ShouldFindProcess() bool {
+	return false
+}
+
+func parsePortNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 || n > 65535 {
+		return 0, errPayload
+	}
+	return n, nil
+}
+
+// parsePortRanges parses payload like `80`, `27000-27100` or `27000-27100/3478`
+func parsePortRanges(payload string) ([]portRange, error) {
+	ranges := []portRange{}
+	for _, item := range strings.Split(payload, "/") {
+		bounds := strings.SplitN(item, "-", 2)
+		start, err := parsePortNumber(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		end := start
+		if len(bounds) == 2 {
+			if end, err = parsePortNumber(bounds[1]); err != nil || end < start {
+				return nil, errPayload
+			}
+		}
+
+		ranges = append(ranges, portRange{start: start, end: end})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.end+1 {
+			if r.end > last.end {
+				last.end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged, nil
+}
+
 func NewPort(port string, adapter string, isSource bool) (*Port, error) {
-	_, err := strconv.Atoi(port)
+	ranges, err := parsePortRanges(port)
 	if err != nil {
-		return nil, errPayload
+		return nil, err
 	}
 	return &Port{
 		adapter:  adapter,
 		port:     port,
 		isSource: isSource,
+		ranges:   ranges,
 	}, nil
 }
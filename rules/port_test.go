@@ -0,0 +1,46 @@
+package rules
+
+import (
+	"strconv"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPort_Match(t *testing.T) {
+	rule, err := NewPort("27000-27100/3478/5000-5100/5050-5200", "DIRECT", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "27000-27100/3478/5000-5100/5050-5200", rule.Payload())
+	assert.Equal(t, []portRange{{3478, 3478}, {5000, 5200}, {27000, 27100}}, rule.ranges)
+
+	cases := map[int]bool{
+		3477:  false,
+		3478:  true,
+		4999:  false,
+		5000:  true,
+		5150:  true,
+		5200:  true,
+		5201:  false,
+		27000: true,
+		27100: true,
+		27101: false,
+	}
+	for port, expected := range cases {
+		metadata := &C.Metadata{DstPort: strconv.Itoa(port)}
+		assert.Equal(t, expected, rule.Match(metadata), port)
+	}
+
+	src, err := NewPort("7777", "DIRECT", true)
+	assert.Nil(t, err)
+	assert.True(t, src.Match(&C.Metadata{SrcPort: "7777", DstPort: "80"}))
+	assert.False(t, src.Match(&C.Metadata{SrcPort: "80", DstPort: "7777"}))
+}
+
+func TestPort_Invalid(t *testing.T) {
+	for _, payload := range []string{"", "abc", "65536", "-1", "100-50", "80/", "1-2-3", "80,443"} {
+		_, err := NewPort(payload, "DIRECT", false)
+		assert.NotNil(t, err, payload)
+	}
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"path/filepath"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+type Process struct {
+	adapter  string
+	process  string
+	nameOnly bool
+}
+
+func (ps *Process) RuleType() C.RuleType {
+	if ps.nameOnly {
+		return C.Process
+	}
+	return C.ProcessPath
+}
+
+func (ps *Process) Match(metadata *C.Metadata) bool {
+	if metadata.ProcessPath == "" {
+		return false
+	}
+
+	if ps.nameOnly {
+		// executable names are case sensitive on linux
+		return filepath.Base(metadata.ProcessPath) == ps.process
+	}
+	return metadata.ProcessPath == ps.process
+}
+
+func (ps *Process) Adapter() string {
+	return ps.adapter
+}
+
+func (ps *Process) Payload() string {
+	return ps.process
+}
+
+func (ps *Process) NoResolveIP() bool {
+	return true
+}
+
+func (ps *Process) ShouldFindProcess() bool {
+	return true
+}
+
+func NewProcess(process string, adapter string, nameOnly bool) *Process {
+	return &Process{
+		adapter:  adapter,
+		process:  process,
+		nameOnly: nameOnly,
+	}
+}
@@ -0,0 +1,21 @@
+package rules
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcess_Match(t *testing.T) {
+	metadata := &C.Metadata{Process: "Firefox", ProcessPath: "/usr/lib/firefox/Firefox"}
+
+	assert.True(t, NewProcess("Firefox", "DIRECT", true).Match(metadata))
+	assert.False(t, NewProcess("firefox", "DIRECT", true).Match(metadata))
+	assert.True(t, NewProcess("/usr/lib/firefox/Firefox", "DIRECT", false).Match(metadata))
+	assert.False(t, NewProcess("Firefox", "DIRECT", false).Match(metadata))
+
+	// connections of an unknown process never match
+	assert.False(t, NewProcess("Firefox", "DIRECT", true).Match(&C.Metadata{}))
+}
@@ -0,0 +1,51 @@
+package rules
+
+import (
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// RuleSetProvider is the part of a rule provider that RULE-SET relies on
+type RuleSetProvider interface {
+	Name() string
+	Match(metadata *C.Metadata) bool
+	ShouldResolveIP() bool
+	ShouldFindProcess() bool
+}
+
+type RuleSet struct {
+	provider    RuleSetProvider
+	adapter     string
+	noResolveIP bool
+}
+
+func (rs *RuleSet) RuleType() C.RuleType {
+	return C.RuleSet
+}
+
+func (rs *RuleSet) Match(metadata *C.Metadata) bool {
+	return rs.provider.Match(metadata)
+}
+
+func (rs *RuleSet) Adapter() string {
+	return rs.adapter
+}
+
+func (rs *RuleSet) Payload() string {
+	return rs.provider.Name()
+}
+
+func (rs *RuleSet) NoResolveIP() bool {
+	return rs.noResolveIP || !rs.provider.ShouldResolveIP()
+}
+
+func (rs *RuleSet) ShouldFindProcess() bool {
+	return rs.provider.ShouldFindProcess()
+}
+
+func NewRuleSet(provider RuleSetProvider, adapter string, noResolveIP bool) *RuleSet {
+	return &RuleSet{
+		provider:    provider,
+		adapter:     adapter,
+		noResolveIP: noResolveIP,
+	}
+}
@@ -0,0 +1,41 @@
+package rules
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ruleSetProvider struct {
+	hosts       map[string]bool
+	resolveIP   bool
+	findProcess bool
+}
+
+func (rp *ruleSetProvider) Name() string                    { return "provider" }
+func (rp *ruleSetProvider) Match(metadata *C.Metadata) bool { return rp.hosts[metadata.Host] }
+func (rp *ruleSetProvider) ShouldResolveIP() bool           { return rp.resolveIP }
+func (rp *ruleSetProvider) ShouldFindProcess() bool         { return rp.findProcess }
+
+func TestRuleSet(t *testing.T) {
+	provider := &ruleSetProvider{hosts: map[string]bool{"example.com": true}}
+	rs := NewRuleSet(provider, "DIRECT", false)
+
+	assert.Equal(t, C.RuleSet, rs.RuleType())
+	assert.Equal(t, "provider", rs.Payload())
+	assert.Equal(t, "DIRECT", rs.Adapter())
+	assert.True(t, rs.Match(&C.Metadata{AddrType: C.AtypDomainName, Host: "example.com"}))
+	assert.False(t, rs.Match(&C.Metadata{AddrType: C.AtypDomainName, Host: "example.org"}))
+
+	// resolving follows the provider unless no-resolve is set
+	assert.True(t, rs.NoResolveIP())
+	provider.resolveIP = true
+	assert.False(t, rs.NoResolveIP())
+	assert.True(t, NewRuleSet(provider, "DIRECT", true).NoResolveIP())
+
+	assert.False(t, rs.ShouldFindProcess())
+	provider.findProcess = true
+	assert.True(t, rs.ShouldFindProcess())
+}
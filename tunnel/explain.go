@@ -0,0 +1,77 @@
+package tunnel
+
+import (
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// Explanation describes how a connection would be routed
+type Explanation struct {
+	Metadata *C.Metadata `json:"metadata"`
+	Mode     string      `json:"mode"`
+	// position of the matched rule, -1 if no rule matched
+	RuleIndex   int     `json:"ruleIndex"`
+	Rule        string  `json:"rule"`
+	RulePayload string  `json:"rulePayload"`
+	Proxy       string  `json:"proxy"`
+	Chains      C.Chain `json:"chains"`
+	Hosts       bool    `json:"hosts"`
+	DNS         bool    `json:"dns"`
+}
+
+// Explain runs metadata through the same steps as an incoming connection
+// without dialing, rule statistics are left untouched
+func Explain(metadata *C.Metadata) (*Explanation, error) {
+	if err := preHandleMetadata(metadata); err != nil {
+		return nil, err
+	}
+	findProcess(metadata)
+
+	configMux.RLock()
+	defer configMux.RUnlock()
+
+	explanation := &Explanation{
+		Metadata:  metadata,
+		Mode:      mode.String(),
+		RuleIndex: -1,
+	}
+
+	var proxy C.Proxy
+	switch mode {
+	case Direct:
+		proxy = proxies["DIRECT"]
+	case Global:
+		proxy = proxies["GLOBAL"]
+	// Rule
+	default:
+		var trace *matchTrace
+		var err error
+		proxy, trace, err = matchRule(metadata)
+		explanation.Hosts = trace.hosts
+		explanation.DNS = trace.dns
+		if err != nil {
+			return nil, err
+		}
+
+		if trace.index != -1 {
+			rule := rules[trace.index]
+			explanation.RuleIndex = trace.index
+			explanation.Rule = rule.RuleType().String()
+			explanation.RulePayload = rule.Payload()
+		}
+	}
+
+	explanation.Proxy = proxy.Name()
+	explanation.Chains = resolveChains(proxy, metadata)
+	return explanation, nil
+}
+
+// resolveChains unwraps proxy groups down to the proxy that would dial,
+// in the same order as the chains of a connection
+func resolveChains(proxy C.Proxy, metadata *C.Metadata) C.Chain {
+	chains := C.Chain{}
+	for proxy != nil {
+		chains = append([]string{proxy.Name()}, chains...)
+		proxy = proxy.Unwrap(metadata)
+	}
+	return chains
+}
@@ -0,0 +1,39 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+func TestExplain(t *testing.T) {
+	setupRules(10)
+	ResetStatistics()
+
+	explanation, err := Explain(&C.Metadata{NetWork: C.TCP, AddrType: C.AtypDomainName, Host: "www.domain3.com", DstPort: "443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.RuleIndex != 3 || explanation.RulePayload != "domain3.com" || explanation.Proxy != "DIRECT" {
+		t.Errorf("unexpected explanation %+v", explanation)
+	}
+
+	if len(explanation.Chains) != 1 || explanation.Chains[0] != "DIRECT" || explanation.DNS {
+		t.Errorf("unexpected explanation %+v", explanation)
+	}
+
+	if Statistic(Rules()[3]).Hits() != 0 {
+		t.Errorf("explain should not count as a hit")
+	}
+
+	explanation, err = Explain(&C.Metadata{NetWork: C.TCP, AddrType: C.AtypIPv4, DstIP: net.ParseIP("1.1.1.1"), DstPort: "443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.RuleIndex != 20 || explanation.Rule != C.MATCH.String() || explanation.Proxy != "REJECT" {
+		t.Errorf("unexpected explanation %+v", explanation)
+	}
+}
@@ -0,0 +1,112 @@
+package tunnel
+
+import (
+	"sync/atomic"
+	"time"
+
+	C "github.com/Dreamacro/clash/constant"
+)
+
+// RuleStatistic records how often a rule matched and the traffic routed by it
+type RuleStatistic struct {
+	hits          int64
+	uploadTotal   int64
+	downloadTotal int64
+	// unix nano of the last match, 0 means never matched
+	lastMatch int64
+}
+
+func (rs *RuleStatistic) Hits() int64 {
+	return atomic.LoadInt64(&rs.hits)
+}
+
+func (rs *RuleStatistic) UploadTotal() int64 {
+	return atomic.LoadInt64(&rs.uploadTotal)
+}
+
+func (rs *RuleStatistic) DownloadTotal() int64 {
+	return atomic.LoadInt64(&rs.downloadTotal)
+}
+
+// LastMatch returns nil when the rule never matched
+func (rs *RuleStatistic) LastMatch() *time.Time {
+	nano := atomic.LoadInt64(&rs.lastMatch)
+	if nano == 0 {
+		return nil
+	}
+
+	t := time.Unix(0, nano)
+	return &t
+}
+
+func (rs *RuleStatistic) hit() {
+	atomic.AddInt64(&rs.hits, 1)
+	atomic.StoreInt64(&rs.lastMatch, time.Now().UnixNano())
+}
+
+// upload and download are called by trackers, rs is nil if no rule matched
+func (rs *RuleStatistic) upload(n int64) {
+	if rs != nil {
+		atomic.AddInt64(&rs.uploadTotal, n)
+	}
+}
+
+func (rs *RuleStatistic) download(n int64) {
+	if rs != nil {
+		atomic.AddInt64(&rs.downloadTotal, n)
+	}
+}
+
+func (rs *RuleStatistic) reset() {
+	atomic.StoreInt64(&rs.hits, 0)
+	atomic.StoreInt64(&rs.uploadTotal, 0)
+	atomic.StoreInt64(&rs.downloadTotal, 0)
+	atomic.StoreInt64(&rs.lastMatch, 0)
+}
+
+func ruleKey(rule C.Rule) string {
+	return rule.RuleType().String() + "," + rule.Payload() + "," + rule.Adapter()
+}
+
+// newRuleStatistics creates the statistics of rules, rules that are
+// unchanged from the previous config keep their statistic
+func newRuleStatistics(rules []C.Rule, previous map[C.Rule]*RuleStatistic) map[C.Rule]*RuleStatistic {
+	kept := make(map[string]*RuleStatistic, len(previous))
+	for rule, rs := range previous {
+		kept[ruleKey(rule)] = rs
+	}
+
+	statistics := make(map[C.Rule]*RuleStatistic, len(rules))
+	for _, rule := range rules {
+		key := ruleKey(rule)
+		if rs, ok := kept[key]; ok {
+			statistics[rule] = rs
+			// duplicated rules don't share a statistic
+			delete(kept, key)
+			continue
+		}
+		statistics[rule] = &RuleStatistic{}
+	}
+	return statistics
+}
+
+// Statistic returns the statistic of a rule in the current config,
+// nil if the rule is nil or no longer in use
+func Statistic(rule C.Rule) *RuleStatistic {
+	if rule == nil {
+		return nil
+	}
+
+	configMux.RLock()
+	defer configMux.RUnlock()
+	return ruleStatistics[rule]
+}
+
+// ResetStatistics clears the statistics of all rules
+func ResetStatistics() {
+	configMux.RLock()
+	defer configMux.RUnlock()
+	for _, rs := range ruleStatistics {
+		rs.reset()
+	}
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"testing"
+
+	C "github.com/Dreamacro/clash/constant"
+	R "github.com/Dreamacro/clash/rules"
+)
+
+func TestStatistic_Hit(t *testing.T) {
+	setupRules(10)
+
+	metadata := &C.Metadata{AddrType: C.AtypDomainName, Host: "www.domain3.com"}
+	for i := 0; i < 3; i++ {
+		if _, _, err := match(metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rule := Rules()[3]
+	statistic := Statistic(rule)
+	if statistic.Hits() != 3 || statistic.LastMatch() == nil {
+		t.Fatalf("expected 3 hits, got %d", statistic.Hits())
+	}
+
+	if other := Statistic(Rules()[4]); other.Hits() != 0 || other.LastMatch() != nil {
+		t.Errorf("unmatched rule has %d hits", other.Hits())
+	}
+
+	statistic.upload(10)
+	statistic.download(20)
+	if statistic.UploadTotal() != 10 || statistic.DownloadTotal() != 20 {
+		t.Errorf("unexpected traffic %d/%d", statistic.UploadTotal(), statistic.DownloadTotal())
+	}
+
+	// unchanged rules keep their statistic after a reload
+	setupRules(10)
+	if Statistic(Rules()[3]).Hits() != 3 {
+		t.Errorf("statistic is lost after reload")
+	}
+
+	UpdateRules([]C.Rule{R.NewDomainSuffix("domain3.com", "REJECT")}, nil)
+	if Statistic(Rules()[0]).Hits() != 0 {
+		t.Errorf("statistic is kept for a changed rule")
+	}
+
+	setupRules(10)
+	match(metadata)
+	ResetStatistics()
+	if Statistic(Rules()[3]).Hits() != 0 || Statistic(Rules()[3]).LastMatch() != nil {
+		t.Errorf("statistic is not reset")
+	}
+}
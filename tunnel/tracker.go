@@ -26,7 +26,8 @@ type trackerInfo struct {
 type tcpTracker struct {
 	C.Conn `json:"-"`
 	*trackerInfo
-	manager *Manager
+	manager   *Manager
+	statistic *RuleStatistic
 }
 
 func (tt *tcpTracker) ID() string {
@@ -38,6 +39,7 @@ func (tt *tcpTracker) Read(b []byte) (int, error) {
 	download := int64(n)
 	tt.manager.Download() <- download
 	tt.DownloadTotal += download
+	tt.statistic.download(download)
 	return n, err
 }
 
@@ -46,6 +48,7 @@ func (tt *tcpTracker) Write(b []byte) (int, error) {
 	upload := int64(n)
 	tt.manager.Upload() <- upload
 	tt.UploadTotal += upload
+	tt.statistic.upload(upload)
 	return n, err
 }
 
@@ -62,8 +65,9 @@ func newTCPTracker(conn C.Conn, manager *Manager, metadata *C.Metadata, rule C.R
 	}
 
 	t := &tcpTracker{
-		Conn:    conn,
-		manager: manager,
+		Conn:      conn,
+		manager:   manager,
+		statistic: Statistic(rule),
 		trackerInfo: &trackerInfo{
 			UUID:     uuid,
 			Start:    time.Now(),
@@ -80,7 +84,8 @@ func newTCPTracker(conn C.Conn, manager *Manager, metadata *C.Metadata, rule C.R
 type udpTracker struct {
 	C.PacketConn `json:"-"`
 	*trackerInfo
-	manager *Manager
+	manager   *Manager
+	statistic *RuleStatistic
 }
 
 func (ut *udpTracker) ID() string {
@@ -92,6 +97,7 @@ func (ut *udpTracker) ReadFrom(b []byte) (int, net.Addr, error) {
 	download := int64(n)
 	ut.manager.Download() <- download
 	ut.DownloadTotal += download
+	ut.statistic.download(download)
 	return n, addr, err
 }
 
@@ -100,6 +106,7 @@ func (ut *udpTracker) WriteTo(b []byte, addr net.Addr) (int, error) {
 	upload := int64(n)
 	ut.manager.Upload() <- upload
 	ut.UploadTotal += upload
+	ut.statistic.upload(upload)
 	return n, err
 }
 
@@ -118,6 +125,7 @@ func newUDPTracker(conn C.PacketConn, manager *Manager, metadata *C.Metadata, ru
 	ut := &udpTracker{
 		PacketConn: conn,
 		manager:    manager,
+		statistic:  Statistic(rule),
 		trackerInfo: &trackerInfo{
 			UUID:     uuid,
 			Start:    time.Now(),
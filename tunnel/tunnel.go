@@ -3,30 +3,41 @@ package tunnel
 import (
 	"fmt"
 	"net"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Dreamacro/clash/adapters/inbound"
 	"github.com/Dreamacro/clash/adapters/provider"
 	"github.com/Dreamacro/clash/component/nat"
+	P "github.com/Dreamacro/clash/component/process"
 	"github.com/Dreamacro/clash/component/resolver"
 	C "github.com/Dreamacro/clash/constant"
 	"github.com/Dreamacro/clash/dns"
 	"github.com/Dreamacro/clash/log"
+	R "github.com/Dreamacro/clash/rules"
 
 	channels "gopkg.in/eapache/channels.v1"
 )
 
 var (
-	tcpQueue     = channels.NewInfiniteChannel()
-	udpQueue     = channels.NewInfiniteChannel()
-	natTable     = nat.New()
-	rules        []C.Rule
-	proxies      = make(map[string]C.Proxy)
-	providers    map[string]provider.ProxyProvider
-	configMux    sync.RWMutex
-	enhancedMode *dns.Resolver
+	tcpQueue       = channels.NewInfiniteChannel()
+	udpQueue       = channels.NewInfiniteChannel()
+	natTable       = nat.New()
+	rules          []C.Rule
+	ruleIndex      = R.NewIndex(nil)
+	ruleStatistics = newRuleStatistics(nil, nil)
+	ruleProviders  map[string]provider.RuleProvider
+	// rulesFindProcess is set when a rule needs the process at load, the
+	// classical providers are asked again since an update may add one
+	rulesFindProcess   bool
+	classicalProviders []provider.RuleProvider
+	proxies        = make(map[string]C.Proxy)
+	providers      map[string]provider.ProxyProvider
+	configMux      sync.RWMutex
+	enhancedMode   *dns.Resolver
 
 	// experimental features
 	ignoreResolveFail bool
@@ -57,13 +68,53 @@ func Rules() []C.Rule {
 	return rules
 }
 
+// RuleProviders return all rule providers
+func RuleProviders() map[string]provider.RuleProvider {
+	return ruleProviders
+}
+
 // UpdateRules handle update rules
-func UpdateRules(newRules []C.Rule) {
+func UpdateRules(newRules []C.Rule, newRuleProviders map[string]provider.RuleProvider) {
 	configMux.Lock()
 	rules = newRules
+	ruleIndex = R.NewIndex(newRules)
+	ruleStatistics = newRuleStatistics(newRules, ruleStatistics)
+	ruleProviders = newRuleProviders
+	rulesFindProcess = false
+	for _, rule := range newRules {
+		if rule.ShouldFindProcess() {
+			rulesFindProcess = true
+			break
+		}
+	}
+	classicalProviders = nil
+	for _, pd := range newRuleProviders {
+		if pd.Behavior() == provider.Classical {
+			classicalProviders = append(classicalProviders, pd)
+		}
+	}
 	configMux.Unlock()
 }
 
+// shouldFindProcess reports whether the rules need the process of a connection
+func shouldFindProcess() bool {
+	configMux.RLock()
+	defer configMux.RUnlock()
+
+	if mode != Rule {
+		return false
+	}
+	if rulesFindProcess {
+		return true
+	}
+	for _, pd := range classicalProviders {
+		if pd.ShouldFindProcess() {
+			return true
+		}
+	}
+	return false
+}
+
 // Proxies return all proxies
 func Proxies() map[string]C.Proxy {
 	return proxies
@@ -156,7 +207,34 @@ func preHandleMetadata(metadata *C.Metadata) error {
 	return nil
 }
 
+// findProcess fills the process owning the source socket when the rules need
+// it, it walks /proc on linux so it is done before taking configMux
+func findProcess(metadata *C.Metadata) {
+	if !shouldFindProcess() {
+		return
+	}
+
+	srcPort, err := strconv.Atoi(metadata.SrcPort)
+	if err != nil {
+		return
+	}
+
+	path, err := P.FindProcessPath(metadata.NetWork.String(), metadata.SrcIP, srcPort)
+	if err == P.ErrPlatformNotSupport {
+		return
+	} else if err != nil {
+		log.Debugln("[Process] find process %s: %v", metadata.String(), err)
+		return
+	}
+
+	log.Debugln("[Process] %s from process %s", metadata.String(), path)
+	metadata.Process = filepath.Base(path)
+	metadata.ProcessPath = path
+}
+
 func resolveMetadata(metadata *C.Metadata) (C.Proxy, C.Rule, error) {
+	findProcess(metadata)
+
 	var proxy C.Proxy
 	var rule C.Rule
 	switch mode {
@@ -297,20 +375,48 @@ func match(metadata *C.Metadata) (C.Proxy, C.Rule, error) {
 	configMux.RLock()
 	defer configMux.RUnlock()
 
+	proxy, trace, err := matchRule(metadata)
+	if err != nil || trace.index == -1 {
+		return proxy, nil, err
+	}
+
+	rule := rules[trace.index]
+	if rs := ruleStatistics[rule]; rs != nil {
+		rs.hit()
+	}
+	return proxy, rule, nil
+}
+
+// matchTrace records the decisions made while matching rules
+type matchTrace struct {
+	// position of the matched rule, -1 if no rule matched
+	index int
+	// destination is found in hosts
+	hosts bool
+	// resolver is queried for the destination
+	dns bool
+}
+
+// matchRule finds the first matching rule, configMux must be held by the caller
+func matchRule(metadata *C.Metadata) (C.Proxy, *matchTrace, error) {
+	trace := &matchTrace{index: -1}
 	var resolved bool
 
 	if node := resolver.DefaultHosts.Search(metadata.Host); node != nil {
 		ip := node.Data.(net.IP)
 		metadata.DstIP = ip
 		resolved = true
+		trace.hosts = true
 	}
 
-	for _, rule := range rules {
+	for idx := 0; idx < len(rules); idx++ {
+		rule := rules[idx]
 		if !resolved && shouldResolveIP(rule, metadata) {
+			trace.dns = true
 			ip, err := resolver.ResolveIP(metadata.Host)
 			if err != nil {
 				if !ignoreResolveFail {
-					return nil, nil, fmt.Errorf("[DNS] resolve %s error: %s", metadata.Host, err.Error())
+					return nil, trace, fmt.Errorf("[DNS] resolve %s error: %s", metadata.Host, err.Error())
 				}
 				log.Debugln("[DNS] resolve %s error: %s", metadata.Host, err.Error())
 			} else {
@@ -320,7 +426,12 @@ func match(metadata *C.Metadata) (C.Proxy, C.Rule, error) {
 			resolved = true
 		}
 
-		if rule.Match(metadata) {
+		// rules of an indexed run share the same resolving behavior,
+		// so the whole run can be checked at once
+		var matched bool
+		idx, matched = ruleIndex.Match(idx, metadata)
+		if matched {
+			rule = rules[idx]
 			adapter, ok := proxies[rule.Adapter()]
 			if !ok {
 				continue
@@ -330,9 +441,11 @@ func match(metadata *C.Metadata) (C.Proxy, C.Rule, error) {
 				log.Debugln("%v UDP is not supported", adapter.Name())
 				continue
 			}
-			return adapter, rule, nil
+
+			trace.index = idx
+			return adapter, trace, nil
 		}
 	}
 
-	return proxies["DIRECT"], nil, nil
+	return proxies["DIRECT"], trace, nil
 }
@@ -0,0 +1,120 @@
+package tunnel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Dreamacro/clash/adapters/outbound"
+	"github.com/Dreamacro/clash/adapters/provider"
+	C "github.com/Dreamacro/clash/constant"
+	R "github.com/Dreamacro/clash/rules"
+)
+
+func setupRules(count int) {
+	newRules := []C.Rule{}
+	for i := 0; i < count; i++ {
+		newRules = append(newRules, R.NewDomainSuffix(fmt.Sprintf("domain%d.com", i), "DIRECT"))
+	}
+	for i := 0; i < count; i++ {
+		ipcidr, _ := R.NewIPCIDR(fmt.Sprintf("10.%d.%d.0/24", i/256%256, i%256), "DIRECT", R.WithIPCIDRNoResolve(true))
+		newRules = append(newRules, ipcidr)
+	}
+	newRules = append(newRules, R.NewMatch("REJECT"))
+
+	UpdateRules(newRules, nil)
+	UpdateProxies(map[string]C.Proxy{
+		"DIRECT": outbound.NewProxy(outbound.NewDirect()),
+		"REJECT": outbound.NewProxy(outbound.NewReject()),
+	}, nil)
+}
+
+func TestMatch_FirstMatch(t *testing.T) {
+	setupRules(100)
+
+	cases := []struct {
+		metadata *C.Metadata
+		payload  string
+	}{
+		{&C.Metadata{AddrType: C.AtypDomainName, Host: "www.domain42.com"}, "domain42.com"},
+		{&C.Metadata{AddrType: C.AtypDomainName, Host: "www.example.org"}, ""},
+		{&C.Metadata{AddrType: C.AtypIPv4, DstIP: net.ParseIP("10.0.42.1")}, "10.0.42.0/24"},
+	}
+
+	for _, c := range cases {
+		_, rule, err := match(c.metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rule.Payload() != c.payload {
+			t.Errorf("%s: expected %s, got %s", c.metadata.String(), c.payload, rule.Payload())
+		}
+	}
+}
+
+func TestShouldFindProcess(t *testing.T) {
+	setupRules(10)
+	if shouldFindProcess() {
+		t.Error("no rule needs the process")
+	}
+
+	UpdateRules([]C.Rule{R.NewProcess("curl", "DIRECT", true)}, nil)
+	if !shouldFindProcess() {
+		t.Error("PROCESS-NAME needs the process")
+	}
+
+	// a classical provider may need it after an update
+	dir, err := ioutil.TempDir("", "clash-tunnel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.yaml")
+	if err := ioutil.WriteFile(path, []byte("payload:\n  - DOMAIN,example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pd := provider.NewRuleSetProvider("rules", provider.Classical, 0, provider.NewFileVehicle(path))
+	if err := pd.Initial(); err != nil {
+		t.Fatal(err)
+	}
+	UpdateRules([]C.Rule{R.NewRuleSet(pd, "DIRECT", false)}, map[string]provider.RuleProvider{"rules": pd})
+	if shouldFindProcess() {
+		t.Error("the provider doesn't need the process")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("payload:\n  - PROCESS-NAME,curl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pd.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if !shouldFindProcess() {
+		t.Error("the updated provider needs the process")
+	}
+}
+
+func benchmarkMatch(b *testing.B, metadata *C.Metadata) {
+	setupRules(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		match(metadata)
+	}
+}
+
+func BenchmarkMatch_DomainMiss(b *testing.B) {
+	benchmarkMatch(b, &C.Metadata{AddrType: C.AtypDomainName, Host: "www.example.org"})
+}
+
+func BenchmarkMatch_DomainHit(b *testing.B) {
+	benchmarkMatch(b, &C.Metadata{AddrType: C.AtypDomainName, Host: "www.domain9999.com"})
+}
+
+func BenchmarkMatch_IPHit(b *testing.B) {
+	benchmarkMatch(b, &C.Metadata{AddrType: C.AtypIPv4, DstIP: net.ParseIP("10.39.15.1")})
+}